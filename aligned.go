@@ -0,0 +1,255 @@
+package dawg
+
+import "encoding/binary"
+
+// inPlace is implemented by readers whose bytes can be used in place, such as
+// a file mapped by Load. The aligned layout is read from them directly.
+type inPlace interface {
+	Bytes() []byte
+}
+
+// alignedNode is a node of the aligned layout in the mapped file
+type alignedNode struct {
+	edges  []byte // the edges of the node, in place in the file
+	count  int    // number of edges
+	final  int    // 1 if the node is final, which is the skip of the first edge
+	cbytes int
+	sbytes int // bytes in the skip count of each edge after the first
+	abytes int
+}
+
+// mappedNode reads the node at the given position in the mapped file into n.
+// It returns false if the file is not mapped, or the node does not lie within
+// it, and then the node must be read with a bitSeeker instead.
+func (d *dawg) mappedNode(node int64, n *alignedNode) bool {
+	if d.data == nil {
+		return false
+	}
+
+	if node == rootNode {
+		node = d.firstNodeOffset
+	}
+
+	n.cbytes, n.abytes = int(d.cbits/8), int(d.abits/8)
+	at := node / 8
+	if node%8 != 0 || at+int64(n.cbytes) >= int64(len(d.data)) {
+		return false
+	}
+
+	head := d.data[at]
+	n.final = int(head >> 7)
+	n.sbytes = int(head & 0xf)
+	start := int(at) + 1 + n.cbytes
+	n.count = 0
+	if head&0x20 == 0 {
+		n.count = int(readUint(d.data[start-n.cbytes:start])) + 1
+	}
+
+	size := 0
+	if n.count > 0 {
+		size = n.count*(n.cbytes+n.sbytes+n.abytes) - n.sbytes
+	}
+	if n.sbytes > 8 || size > len(d.data)-start {
+		return false
+	}
+
+	n.edges = d.data[start : start+size]
+	return true
+}
+
+// offset returns the position of edge i in the edges
+func (n *alignedNode) offset(i int) int {
+	if i == 0 {
+		return 0
+	}
+	return i*(n.cbytes+n.sbytes+n.abytes) - n.sbytes
+}
+
+// code returns the stored character of edge i
+func (n *alignedNode) code(i int) uint64 {
+	at := n.offset(i)
+	return readUint(n.edges[at : at+n.cbytes])
+}
+
+// searchMapped returns the index of the first edge whose character is not
+// less than ch, or the number of edges if there is none.
+func (d *dawg) searchMapped(n *alignedNode, ch rune) int {
+	lo, hi := 0, n.count
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if letterOf(d.alphabet, n.code(mid)) < ch {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
+
+// mappedEdge reads edge i
+func (d *dawg) mappedEdge(n *alignedNode, i int) edgeResult {
+	at := n.offset(i)
+	edge := edgeResult{
+		ch:    letterOf(d.alphabet, readUint(n.edges[at:at+n.cbytes])),
+		count: n.final,
+	}
+	at += n.cbytes
+
+	if i > 0 {
+		edge.count = int(readUint(n.edges[at : at+n.sbytes]))
+		at += n.sbytes
+	}
+	edge.node = int64(readUint(n.edges[at : at+n.abytes]))
+	return edge
+}
+
+// alignedFinal returns whether the node at the given position is final
+func (d *dawg) alignedFinal(r *bitSeeker, node int64) bool {
+	if at := node / 8; node%8 == 0 && at < int64(len(d.data)) {
+		return d.data[at]>>7 == 1
+	}
+	r.Seek(node, 0)
+	return r.ReadBits(1) == 1
+}
+
+// indexOfMapped is indexOf for the aligned layout in a mapped file. The nodes
+// are read in place, without a bitSeeker. It returns false if a node does not
+// lie within the file, and then the word must be looked up with indexOf.
+func (d *dawg) indexOfMapped(input string) (int, bool) {
+	skipped := 0
+	node := int64(rootNode)
+	final := d.hasEmptyWord
+	var n alignedNode
+
+	for pos := 0; pos < len(input); {
+		letter, size := d.nextLetter(input[pos:])
+		pos += size
+
+		if !d.mappedNode(node, &n) {
+			return 0, false
+		}
+
+		i := d.searchMapped(&n, letter)
+		if i == n.count {
+			return -1, true
+		}
+
+		edge := d.mappedEdge(&n, i)
+		if edge.ch != letter {
+			return -1, true
+		}
+
+		node = edge.node
+		skipped += edge.count
+		at := node / 8
+		if node%8 != 0 || at >= int64(len(d.data)) {
+			return 0, false
+		}
+		final = d.data[at]>>7 == 1
+	}
+
+	if final {
+		return skipped, true
+	}
+	return -1, true
+}
+
+// getAlignedEdge is getEdge for a node in the aligned layout
+func (d *dawg) getAlignedEdge(r *bitSeeker, node int64, ch rune) (edgeEnd, bool, bool) {
+	var edge edgeResult
+	var n alignedNode
+	if d.mappedNode(node, &n) {
+		i := d.searchMapped(&n, ch)
+		if i == n.count {
+			return edgeEnd{}, false, false
+		}
+		edge = d.mappedEdge(&n, i)
+	} else {
+		list := d.readEdgeList(r, node)
+		i := d.searchEdges(r, list, ch)
+		if i == list.count {
+			return edgeEnd{}, false, false
+		}
+		edge = d.edgeAt(r, list, i)
+	}
+
+	if edge.ch != ch {
+		return edgeEnd{}, false, false
+	}
+	return edgeEnd{node: edge.node, count: edge.count}, d.alignedFinal(r, edge.node), true
+}
+
+// getAlignedEdgeSpan is getEdgeSpan for a node in the aligned layout
+func (d *dawg) getAlignedEdgeSpan(r *bitSeeker, node int64, total int, ch rune) edgeSpan {
+	// edgeAt reads edge i, which must exist
+	var edgeAt func(i int) edgeResult
+	var i, count int
+	var n alignedNode
+	if d.mappedNode(node, &n) {
+		i, count = d.searchMapped(&n, ch), n.count
+		edgeAt = func(i int) edgeResult { return d.mappedEdge(&n, i) }
+	} else {
+		list := d.readEdgeList(r, node)
+		i, count = d.searchEdges(r, list, ch), list.count
+		edgeAt = func(i int) edgeResult { return d.edgeAt(r, list, i) }
+	}
+
+	var span edgeSpan
+	var edge edgeResult
+	span.before = total
+	if i < count {
+		edge = edgeAt(i)
+		span.before = edge.count
+	}
+
+	span.through = span.before
+	if i < count && edge.ch == ch {
+		span.through = total
+		if i+1 < count {
+			span.through = edgeAt(i + 1).count
+		}
+		span.node = edge.node
+		span.final = d.alignedFinal(r, edge.node)
+		span.ok = true
+	}
+	return span
+}
+
+// getAlignedNode is getNode for a node in the aligned layout
+func (d *dawg) getAlignedNode(r *bitSeeker, node int64) nodeResult {
+	var n alignedNode
+	if d.mappedNode(node, &n) {
+		result := nodeResult{node: node, final: n.final == 1}
+		for i := 0; i < n.count; i++ {
+			result.edges = append(result.edges, d.mappedEdge(&n, i))
+		}
+		return result
+	}
+
+	list := d.readEdgeList(r, node)
+	result := nodeResult{node: node, final: list.final == 1}
+	for i := 0; i < list.count; i++ {
+		result.edges = append(result.edges, d.edgeAt(r, list, i))
+	}
+	return result
+}
+
+// readUint returns the big-endian number in the bytes
+func readUint(data []byte) uint64 {
+	switch len(data) {
+	case 1:
+		return uint64(data[0])
+	case 2:
+		return uint64(binary.BigEndian.Uint16(data))
+	case 4:
+		return uint64(binary.BigEndian.Uint32(data))
+	case 8:
+		return binary.BigEndian.Uint64(data)
+	}
+
+	var value uint64
+	for _, b := range data {
+		value = value<<8 | uint64(b)
+	}
+	return value
+}
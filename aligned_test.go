@@ -0,0 +1,154 @@
+package dawg_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/smhanov/dawg"
+)
+
+func TestAligned(t *testing.T) {
+	words := []string{"", "blip", "cat", "catnip", "cats", "dog", "dogs", "éclair"}
+	builder := dawg.New()
+	for i, word := range words {
+		builder.AddWithValue(word, uint64(i*1000))
+	}
+	finder := builder.Finish()
+
+	var packed, aligned bytes.Buffer
+	if _, err := finder.Write(&packed); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := finder.WriteWithOptions(&aligned, dawg.WriteOptions{Aligned: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if aligned.Len() <= packed.Len() {
+		t.Errorf("aligned file has %d bytes, packed file has %d", aligned.Len(), packed.Len())
+	}
+
+	if err := dawg.Verify(bytes.NewReader(aligned.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := dawg.Validate(bytes.NewReader(aligned.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := dawg.Read(bytes.NewReader(aligned.Bytes()), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a memory mapped file is read in place
+	filename := filepath.Join(t.TempDir(), "aligned.dawg")
+	if err := os.WriteFile(filename, aligned.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := dawg.Load(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer loaded.Close()
+
+	for _, finder := range []dawg.Finder{read, loaded} {
+		for i, word := range words {
+			if index := finder.IndexOf(word); index != i {
+				t.Errorf("IndexOf(%q) returned %d", word, index)
+			}
+
+			if found, _ := finder.AtIndex(i); found != word {
+				t.Errorf("AtIndex(%d) returned %q", i, found)
+			}
+
+			if value, ok := finder.Value(word); !ok || value != uint64(i*1000) {
+				t.Errorf("Value(%q) returned %d", word, value)
+			}
+		}
+
+		if results := finder.FindAllPrefixesOf("catnips"); len(results) != 3 {
+			t.Errorf("FindAllPrefixesOf returned %v", results)
+		}
+
+		if rank := finder.Rank("cato"); rank != 4 {
+			t.Errorf("Rank(cato) returned %d", rank)
+		}
+
+		var enumerated []string
+		finder.Enumerate(func(index int, word []rune, final bool) dawg.EnumerationResult {
+			if final {
+				enumerated = append(enumerated, string(word))
+			}
+			return dawg.Continue
+		})
+		if len(enumerated) != len(words) {
+			t.Errorf("Enumerate found %q", enumerated)
+		}
+	}
+
+	// converting back gives the original file
+	var converted bytes.Buffer
+	if _, err := read.WriteWithOptions(&converted, dawg.WriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(converted.Bytes(), packed.Bytes()) {
+		t.Errorf("converting back to the packed layout gave a different file")
+	}
+}
+
+// benchmarkIndexOf looks up the words in a dawg saved in the given layout
+// and loaded from the file.
+func benchmarkIndexOf(b *testing.B, words []string, aligned bool) {
+	filename := filepath.Join(b.TempDir(), "test.dawg")
+	f, err := os.Create(filename)
+	if err != nil {
+		b.Fatal(err)
+	}
+	_, err = createDawg(words).WriteWithOptions(f, dawg.WriteOptions{Aligned: aligned})
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	finder, err := dawg.Load(filename)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer finder.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		finder.IndexOf(words[i*7919%len(words)])
+	}
+}
+
+// wideWords returns words for a dawg whose root has 20000 edges
+func wideWords() []string {
+	var words []string
+	for ch := rune(0x4e00); ch < 0x4e00+20000; ch++ {
+		words = append(words, string(ch)+"a")
+	}
+	return words
+}
+
+func BenchmarkIndexOfAligned(b *testing.B) {
+	benchmarkIndexOf(b, numberWords(100000), true)
+}
+
+func BenchmarkIndexOfPacked(b *testing.B) {
+	benchmarkIndexOf(b, numberWords(100000), false)
+}
+
+func BenchmarkIndexOfWideAligned(b *testing.B) {
+	benchmarkIndexOf(b, wideWords(), true)
+}
+
+func BenchmarkIndexOfWidePacked(b *testing.B) {
+	benchmarkIndexOf(b, wideWords(), false)
+}
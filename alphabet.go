@@ -0,0 +1,97 @@
+package dawg
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// maxLetter is the largest character that can be stored in an alphabet
+const maxLetter = 1<<31 - 1
+
+// alphabetOf returns the characters used by the edges of the nodes, in
+// increasing order.
+func alphabetOf(nodes []node) []rune {
+	used := make(map[rune]bool)
+	for _, node := range nodes {
+		for _, edge := range node.edges {
+			used[edge.ch] = true
+		}
+	}
+
+	alphabet := make([]rune, 0, len(used))
+	for ch := range used {
+		alphabet = append(alphabet, ch)
+	}
+	sort.Slice(alphabet, func(i, j int) bool {
+		return alphabet[i] < alphabet[j]
+	})
+	return alphabet
+}
+
+// alphabetBits returns the number of bits the alphabet takes in the header.
+// It is stored as its length, followed by the difference between each
+// character and the one before it.
+func alphabetBits(alphabet []rune) uint64 {
+	length := unsignedLength(uint64(len(alphabet)))
+	prev := rune(0)
+	for _, ch := range alphabet {
+		length += unsignedLength(uint64(ch - prev))
+		prev = ch
+	}
+	return length * 8
+}
+
+// codeBits returns the number of bits needed for the codes of the alphabet
+func codeBits(alphabet []rune) uint64 {
+	if len(alphabet) == 0 {
+		return 0
+	}
+	return uint64(bits.Len(uint(len(alphabet) - 1)))
+}
+
+func writeAlphabet(w *bitWriter, alphabet []rune) {
+	writeUnsigned(w, uint64(len(alphabet)))
+	prev := rune(0)
+	for _, ch := range alphabet {
+		writeUnsigned(w, uint64(ch-prev))
+		prev = ch
+	}
+}
+
+// readAlphabet reads the alphabet of a file which is size bytes long. It
+// returns false if the characters are not in increasing order.
+func readAlphabet(r *bitSeeker, size int64) ([]rune, bool) {
+	length := readUnsigned(r)
+	if length == 0 || length > uint64(size) {
+		return nil, false
+	}
+
+	alphabet := make([]rune, length)
+	prev := uint64(0)
+	for i := range alphabet {
+		diff := readUnsigned(r)
+		if i > 0 && diff == 0 || diff > maxLetter-prev {
+			return nil, false
+		}
+		prev += diff
+		alphabet[i] = rune(prev)
+	}
+	return alphabet, true
+}
+
+// letterOf returns the character for a code in the file. Without an
+// alphabet, the code is the character itself. Codes beyond the end of the
+// alphabet give -1.
+func letterOf(alphabet []rune, code uint64) rune {
+	if alphabet == nil {
+		return rune(code)
+	} else if code < uint64(len(alphabet)) {
+		return alphabet[code]
+	}
+	return -1
+}
+
+// readLetter reads the character of an edge
+func (d *dawg) readLetter(r *bitSeeker) rune {
+	return letterOf(d.alphabet, r.ReadBits(d.cbits))
+}
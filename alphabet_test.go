@@ -0,0 +1,100 @@
+package dawg_test
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/smhanov/dawg"
+)
+
+func encodedSize(t *testing.T, words []string) int {
+	t.Helper()
+	var buffer bytes.Buffer
+	if _, err := createDawg(words).Write(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Len()
+}
+
+func TestAlphabet(t *testing.T) {
+	words := benchmarkWords(2000)
+	plain := encodedSize(t, words)
+
+	// one large character must not make every edge larger
+	words = append(words, "fffff\U0001F600", "中文")
+	sort.Strings(words)
+	size := encodedSize(t, words)
+	if size > plain+plain/20 {
+		t.Errorf("adding two words grew the dawg from %d to %d bytes", plain, size)
+	}
+
+	builder := dawg.New()
+	for i, word := range words {
+		builder.AddWithValue(word, uint64(i))
+	}
+	finder := builder.Finish()
+
+	for _, aligned := range []bool{false, true} {
+		var buffer bytes.Buffer
+		if _, err := finder.WriteWithOptions(&buffer, dawg.WriteOptions{Aligned: aligned}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := dawg.Validate(bytes.NewReader(buffer.Bytes())); err != nil {
+			t.Fatal(err)
+		}
+
+		read, err := dawg.Read(bytes.NewReader(buffer.Bytes()), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testDawg(t, read, words)
+
+		if value, ok := read.Value("中文"); !ok || value != uint64(len(words)-1) {
+			t.Errorf("Value returned %d, %v", value, ok)
+		}
+
+		// characters that are not in the alphabet still sort in order
+		for _, word := range []string{"0g", "fffff~", "fffff\U0001F601", "中"} {
+			expected := sort.SearchStrings(words, word)
+			if rank := read.Rank(word); rank != expected {
+				t.Errorf("Rank(%q) returned %d, expected %d", word, rank, expected)
+			}
+			if index := read.IndexOf(word); index != -1 {
+				t.Errorf("IndexOf(%q) returned %d", word, index)
+			}
+		}
+	}
+}
+
+func TestAlphabetCorrupt(t *testing.T) {
+	words := []string{"ab", "abc", "a\U0001F600", "b", "bc\U0001F600", "c\U0001F600\U0001F600"}
+
+	var buffer bytes.Buffer
+	if _, err := createDawg(words).Write(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	// Flipping any bit must be detected, or leave a dawg that can be used
+	// safely.
+	data := buffer.Bytes()
+	for i := 18 * 8; i < len(data)*8; i++ {
+		corrupt := append([]byte{}, data...)
+		corrupt[i/8] ^= 1 << (i % 8)
+		_, err := dawg.Validate(bytes.NewReader(corrupt))
+		if err == nil {
+			finder, err := dawg.Read(bytes.NewReader(corrupt), 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			finder.Enumerate(func(index int, word []rune, final bool) dawg.EnumerationResult {
+				return dawg.Continue
+			})
+		} else if !errors.Is(err, dawg.ErrCorrupt) {
+			t.Errorf("Validate with bit %d flipped returned %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package dawg
+
+import "sort"
+
+// AnagramOptions controls the words returned by Anagrams
+type AnagramOptions struct {
+	// Number of blanks, each of which can stand for any character
+	Blanks int
+
+	// Words shorter than this are not returned
+	MinLength int
+
+	// Only return words that use every letter and blank
+	UseAll bool
+}
+
+type anagramSearch struct {
+	d       *dawg
+	r       bitSeeker
+	opts    AnagramOptions
+	counts  map[rune]int
+	blanks  int
+	runes   []rune
+	results []FindResult
+}
+
+// Anagrams returns the words in the dawg that can be spelled using the given
+// letters, in alphabetical order. Each letter can be used as many times as it
+// appears. It will panic if the dawg is not finished.
+func (d *dawg) Anagrams(letters string, opts AnagramOptions) []FindResult {
+	d.checkFinished()
+	if !d.begin() {
+		return nil
+	}
+	defer d.release()
+
+	search := &anagramSearch{
+		d:      d,
+		r:      newBitSeeker(d.r),
+		opts:   opts,
+		counts: make(map[rune]int),
+		blanks: opts.Blanks,
+	}
+	defer d.addCacheStats(&search.r)
+
+	runes := d.letters(letters)
+	for _, letter := range runes {
+		search.counts[letter]++
+	}
+
+	search.search(rootNode, d.hasEmptyWord, 0, len(runes)+opts.Blanks)
+	return search.results
+}
+
+// search finds the words beneath the node, which is reached using the runes
+// so far. remaining is the number of letters and blanks that are not used.
+func (s *anagramSearch) search(node int64, final bool, index int, remaining int) {
+	if final && len(s.runes) >= s.opts.MinLength && (!s.opts.UseAll || remaining == 0) {
+		s.results = append(s.results, FindResult{
+			Word:  s.d.wordOf(s.runes),
+			Index: index,
+		})
+	}
+
+	if remaining == 0 {
+		return
+	}
+
+	// without blanks, only the edges for the letters that are left need to be
+	// followed.
+	var ranges []runeRange
+	if s.blanks == 0 {
+		for letter, count := range s.counts {
+			if count > 0 {
+				ranges = append(ranges, runeRange{letter, letter})
+			}
+		}
+		sort.Slice(ranges, func(i, j int) bool {
+			return ranges[i].lo < ranges[j].lo
+		})
+	}
+
+	s.d.followEdges(&s.r, node, ranges, s.blanks > 0, func(ch rune, next int64, count int, nextFinal bool) EnumerationResult {
+		// A letter is always used before a blank, since a blank can do
+		// anything the letter can.
+		if s.counts[ch] > 0 {
+			s.counts[ch]--
+			defer func() { s.counts[ch]++ }()
+		} else {
+			s.blanks--
+			defer func() { s.blanks++ }()
+		}
+
+		s.runes = append(s.runes, ch)
+		s.search(next, nextFinal, index+count, remaining-1)
+		s.runes = s.runes[:len(s.runes)-1]
+		return Continue
+	})
+}
@@ -0,0 +1,60 @@
+package dawg_test
+
+import (
+	"testing"
+
+	"github.com/smhanov/dawg"
+)
+
+func TestAnagrams(t *testing.T) {
+	words := []string{
+		"",
+		"a",
+		"act",
+		"at",
+		"cat",
+		"cats",
+		"scat",
+		"taco",
+		"tact",
+	}
+
+	finder := createDawg(words)
+
+	tests := []struct {
+		letters  string
+		opts     dawg.AnagramOptions
+		expected []string
+	}{
+		{"tac", dawg.AnagramOptions{}, []string{"", "a", "act", "at", "cat"}},
+		{"tac", dawg.AnagramOptions{MinLength: 2}, []string{"act", "at", "cat"}},
+		{"tac", dawg.AnagramOptions{UseAll: true}, []string{"act", "cat"}},
+		{"tac", dawg.AnagramOptions{Blanks: 1, UseAll: true}, []string{"cats", "scat", "taco", "tact"}},
+		{"ttac", dawg.AnagramOptions{UseAll: true}, []string{"tact"}},
+		{"", dawg.AnagramOptions{Blanks: 2, MinLength: 1}, []string{"a", "at"}},
+		{"xyz", dawg.AnagramOptions{MinLength: 1}, nil},
+	}
+
+	for _, test := range tests {
+		results := finder.Anagrams(test.letters, test.opts)
+		var found []string
+		for _, result := range results {
+			if finder.IndexOf(result.Word) != result.Index {
+				t.Errorf("Anagrams(%q) returned %q with index %d", test.letters, result.Word, result.Index)
+			}
+			found = append(found, result.Word)
+		}
+
+		if len(found) != len(test.expected) {
+			t.Errorf("Anagrams(%q, %+v) returned %v, expected %v", test.letters, test.opts, found, test.expected)
+			continue
+		}
+
+		for i := range found {
+			if found[i] != test.expected[i] {
+				t.Errorf("Anagrams(%q, %+v) returned %v, expected %v", test.letters, test.opts, found, test.expected)
+				break
+			}
+		}
+	}
+}
@@ -2,23 +2,29 @@ package dawg
 
 import (
 	"encoding/binary"
+	"fmt"
 	"io"
-	"log"
 )
 
 type bitWriter struct {
 	io.Writer
 	cache uint8
 	used  int
+	err   error // first error encountered while writing
 }
 
 // NewBitWriter creates a new BitWriter from an io writer.
 func newBitWriter(w io.Writer) *bitWriter {
-	return &bitWriter{w, 0, 0}
+	return &bitWriter{Writer: w}
 }
 
+// WriteBits writes the low n bits of data. After an error, all further
+// writes return that error.
 func (w *bitWriter) WriteBits(data uint64, n int) error {
 	var mask uint8
+	if w.err != nil {
+		return w.err
+	}
 	for n > 0 {
 		written := n
 		if written+w.used > 8 {
@@ -32,6 +38,7 @@ func (w *bitWriter) WriteBits(data uint64, n int) error {
 		if w.used == 8 {
 			_, err := w.Write([]byte{w.cache})
 			if err != nil {
+				w.err = err
 				return err
 			}
 			w.used = 0
@@ -43,11 +50,17 @@ func (w *bitWriter) WriteBits(data uint64, n int) error {
 }
 
 func (w *bitWriter) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
 	if w.used > 0 {
 		_, err := w.Write([]byte{w.cache << (8 - w.used)})
 		if err != nil {
+			w.err = err
 			return err
 		}
+		w.cache = 0
+		w.used = 0
 	}
 	return nil
 }
@@ -139,6 +152,11 @@ type bitSeeker struct {
 	buffer [8]byte
 	slice  []byte
 	cache  uint64
+
+	// lookups in the node cache made with this seeker, which have not been
+	// added to the totals of the dawg yet
+	cacheHits   int64
+	cacheMisses int64
 }
 
 // NewBitSeeker creates a new bitreaderat
@@ -152,7 +170,11 @@ func newBitSeeker(r io.ReaderAt) bitSeeker {
 func (r *bitSeeker) nextWord(at int64) uint64 {
 	at = at >> 6
 	if at != r.have {
-		r.ReadAt(r.slice, at<<3)
+		n, _ := r.ReadAt(r.slice, at<<3)
+		// anything past the end of the data reads as zero.
+		for i := n; i < len(r.slice); i++ {
+			r.slice[i] = 0
+		}
 		r.have = at
 		r.cache = binary.BigEndian.Uint64(r.slice)
 	}
@@ -194,7 +216,7 @@ func (r *bitSeeker) Seek(offset int64, whence int) (int64, error) {
 	case io.SeekCurrent:
 		r.p += offset
 	default:
-		log.Panicf("Seek whence=%d not supported", whence)
+		panic(fmt.Errorf("dawg: seek whence=%d not supported", whence))
 	}
 	return r.p, nil
 }
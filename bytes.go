@@ -0,0 +1,84 @@
+package dawg
+
+import "unicode/utf8"
+
+// AddBytes adds a key of arbitrary bytes to the dawg. Keys must be added in
+// byte order. It will panic under the same conditions as Add, or if words
+// have already been added with Add.
+func (d *dawg) AddBytes(word []byte) {
+	if err := d.TryAddBytes(word); err != nil {
+		panic(err)
+	}
+}
+
+// TryAddBytes adds a key of arbitrary bytes to the dawg. The first key added
+// decides whether the dawg holds bytes or strings. Once it holds bytes, each
+// byte is one letter, so keys need not be valid UTF-8, and the methods that
+// take strings use the bytes of the string. Functions such as EnumFn receive
+// each byte as a rune. It returns ErrMixedKeys if words
+// have already been added with Add, or an error under the same conditions as
+// TryAdd.
+func (d *dawg) TryAddBytes(word []byte) error {
+	if d.numAdded == 0 && !d.finished {
+		d.byteKeys = true
+	} else if !d.byteKeys && !d.finished {
+		return ErrMixedKeys
+	}
+
+	letters := make([]rune, len(word))
+	for i, b := range word {
+		letters[i] = rune(b)
+	}
+	return d.add(letters)
+}
+
+// IndexOfBytes returns the index of the key, or -1 if it is not in the
+// dawg.
+func (d *dawg) IndexOfBytes(input []byte) int {
+	return d.IndexOf(string(input))
+}
+
+// AtIndexBytes returns the key with the given index. For a dawg of strings,
+// it returns the UTF-8 encoding of the word.
+func (d *dawg) AtIndexBytes(index int) ([]byte, error) {
+	word, err := d.AtIndex(index)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(word), nil
+}
+
+// letters returns the letters of the word that label the edges of the dawg.
+// These are its runes, or its bytes if the dawg holds bytes.
+func (d *dawg) letters(word string) []rune {
+	if !d.byteKeys {
+		return []rune(word)
+	}
+
+	letters := make([]rune, len(word))
+	for i := 0; i < len(word); i++ {
+		letters[i] = rune(word[i])
+	}
+	return letters
+}
+
+// wordOf returns the word spelled by the letters, the reverse of letters.
+func (d *dawg) wordOf(letters []rune) string {
+	if !d.byteKeys {
+		return string(letters)
+	}
+
+	word := make([]byte, len(letters))
+	for i, letter := range letters {
+		word[i] = byte(letter)
+	}
+	return string(word)
+}
+
+// nextLetter returns the first letter of s and its length in bytes.
+func (d *dawg) nextLetter(s string) (rune, int) {
+	if d.byteKeys {
+		return rune(s[0]), 1
+	}
+	return utf8.DecodeRuneInString(s)
+}
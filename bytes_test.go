@@ -0,0 +1,207 @@
+package dawg_test
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"reflect"
+	"regexp/syntax"
+	"sort"
+	"testing"
+
+	"github.com/smhanov/dawg"
+)
+
+// randomKeys returns n unique keys of random bytes in byte order
+func randomKeys(n int) [][]byte {
+	rng := rand.New(rand.NewSource(1))
+	seen := make(map[string]bool)
+	var keys [][]byte
+	for len(keys) < n {
+		key := make([]byte, rng.Intn(6))
+		for i := range key {
+			// few values, so that keys share prefixes
+			key[i] = []byte{0, 'a', 0x7f, 0x80, 0xc3, 0xa9, 0xff}[rng.Intn(7)]
+		}
+		if !seen[string(key)] {
+			seen[string(key)] = true
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i], keys[j]) < 0
+	})
+	return keys
+}
+
+func testKeys(t *testing.T, finder dawg.Finder, keys [][]byte) {
+	t.Helper()
+	if finder.NumAdded() != len(keys) {
+		t.Fatalf("NumAdded is %d, expected %d", finder.NumAdded(), len(keys))
+	}
+
+	for i, key := range keys {
+		if index := finder.IndexOfBytes(key); index != i {
+			t.Errorf("IndexOfBytes(%q) returned %d, expected %d", key, index, i)
+		}
+
+		found, err := finder.AtIndexBytes(i)
+		if err != nil || !bytes.Equal(found, key) {
+			t.Errorf("AtIndexBytes(%d) returned %q, %v, expected %q", i, found, err, key)
+		}
+	}
+
+	if index := finder.IndexOfBytes([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}); index != -1 {
+		t.Errorf("IndexOfBytes found a missing key at %d", index)
+	}
+}
+
+func TestBytes(t *testing.T) {
+	keys := randomKeys(500)
+	builder := dawg.New()
+	for _, key := range keys {
+		builder.AddBytes(key)
+	}
+	finder := builder.Finish()
+	testKeys(t, finder, keys)
+
+	// the string methods use the bytes of the string
+	if index := finder.IndexOf(string(keys[100])); index != 100 {
+		t.Errorf("IndexOf returned %d, expected 100", index)
+	}
+
+	var found []string
+	finder.Enumerate(func(index int, word []rune, final bool) dawg.EnumerationResult {
+		if final {
+			key := make([]byte, len(word))
+			for i, letter := range word {
+				key[i] = byte(letter)
+			}
+			found = append(found, string(key))
+		}
+		return dawg.Continue
+	})
+	for i, key := range keys {
+		if i >= len(found) || found[i] != string(key) {
+			t.Fatalf("Enumerate did not find %q at %d", key, i)
+		}
+	}
+
+	for _, aligned := range []bool{false, true} {
+		var buffer bytes.Buffer
+		if _, err := finder.WriteWithOptions(&buffer, dawg.WriteOptions{Aligned: aligned}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := dawg.Validate(bytes.NewReader(buffer.Bytes())); err != nil {
+			t.Fatal(err)
+		}
+
+		read, err := dawg.Read(bytes.NewReader(buffer.Bytes()), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testKeys(t, read, keys)
+	}
+}
+
+func TestBytesOrder(t *testing.T) {
+	builder := dawg.New()
+	builder.AddBytes([]byte("caf\xc3\xa9"))
+	if err := builder.TryAddBytes([]byte("caf\xff")); err != nil {
+		t.Errorf("TryAddBytes returned %v", err)
+	}
+	if err := builder.TryAddBytes([]byte("caf\xfe")); err != dawg.ErrOutOfOrder {
+		t.Errorf("TryAddBytes returned %v, expected ErrOutOfOrder", err)
+	}
+	if err := builder.TryAdd("cafz"); err != dawg.ErrOutOfOrder {
+		t.Errorf("TryAdd returned %v, expected ErrOutOfOrder", err)
+	}
+	if err := builder.TryAdd("caf\xff\x00"); err != nil {
+		t.Errorf("TryAdd returned %v", err)
+	}
+
+	finder := builder.Finish()
+	testKeys(t, finder, [][]byte{[]byte("caf\xc3\xa9"), []byte("caf\xff"), []byte("caf\xff\x00")})
+
+	builder = dawg.New()
+	builder.Add("cat")
+	if err := builder.TryAddBytes([]byte("dog")); err != dawg.ErrMixedKeys {
+		t.Errorf("TryAddBytes returned %v, expected ErrMixedKeys", err)
+	}
+}
+
+func TestUnsortedBytes(t *testing.T) {
+	keys := randomKeys(300)
+	builder := dawg.NewUnsortedBuilder(256)
+	for _, i := range rand.New(rand.NewSource(2)).Perm(len(keys)) {
+		builder.AddBytes(keys[i])
+	}
+	builder.AddBytes(keys[0])
+
+	testKeys(t, builder.Finish(), keys)
+}
+
+func TestMatchBytes(t *testing.T) {
+	keys := []string{"cafe", "caf\xc3\xa9", "x\x00", "x\xc3", "x\xff"}
+	builder := dawg.New()
+	for _, key := range keys {
+		builder.AddBytes([]byte(key))
+	}
+	finder := builder.Finish()
+
+	// collect returns an EnumFn that appends each byte key to results
+	collect := func(results *[]string) dawg.EnumFn {
+		return func(index int, word []rune, final bool) dawg.EnumerationResult {
+			key := make([]byte, len(word))
+			for i, letter := range word {
+				key[i] = byte(letter)
+			}
+			*results = append(*results, string(key))
+			return dawg.Continue
+		}
+	}
+
+	globs := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"caf\xc3\xa9", []string{"caf\xc3\xa9"}},
+		{"x\xff", []string{"x\xff"}},
+		{"x?", []string{"x\x00", "x\xc3", "x\xff"}},
+		{"caf?\xa9", []string{"caf\xc3\xa9"}},
+		{"x[\xc3-\xff]", []string{"x\xc3", "x\xff"}},
+	}
+	for _, test := range globs {
+		var results []string
+		finder.Match(test.pattern, collect(&results))
+		if !reflect.DeepEqual(results, test.expected) {
+			t.Errorf("Match(%q) returned %q, expected %q", test.pattern, results, test.expected)
+		}
+	}
+
+	regexps := []struct {
+		pattern  string
+		expected []string
+		err      error
+	}{
+		{"café", []string{"caf\xc3\xa9"}, nil},
+		{"caf..", []string{"caf\xc3\xa9"}, nil},
+		{"x[^a]", []string{"x\x00", "x\xc3", "x\xff"}, nil},
+		{"(?i)CAFÉ", nil, dawg.ErrByteRegexp},
+		{"x[aé]", nil, dawg.ErrByteRegexp},
+	}
+	for _, test := range regexps {
+		re, err := syntax.Parse(test.pattern, syntax.Perl)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var results []string
+		err = finder.MatchRegexp(re, collect(&results))
+		if !errors.Is(err, test.err) || !reflect.DeepEqual(results, test.expected) {
+			t.Errorf("MatchRegexp(%q) returned %q, %v, expected %q", test.pattern, results, err, test.expected)
+		}
+	}
+}
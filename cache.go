@@ -0,0 +1,155 @@
+package dawg
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+// LoadOptions controls how a dawg is accessed after it is loaded
+type LoadOptions struct {
+	// Number of nodes to decode when the dawg is loaded and keep in memory.
+	// The nodes nearest the root are chosen, since most lookups pass through
+	// them. Zero means nothing is cached.
+	CacheNodes int
+}
+
+// CacheStats describes how well the node cache is working
+type CacheStats struct {
+	// Number of nodes in the cache
+	Nodes int
+
+	// Number of times a node was found in the cache, or had to be decoded
+	// from the file
+	Hits   int64
+	Misses int64
+}
+
+// cachedNode is a decoded node, along with whether the node that each edge
+// leads to is final
+type cachedNode struct {
+	nodeResult
+	finals []bool
+}
+
+// LoadWithOptions loads the dawg from a file, like Load.
+func LoadWithOptions(filename string, opts LoadOptions) (Finder, error) {
+	finder, err := Load(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.CacheNodes > 0 {
+		finder.(*dawg).buildCache(opts.CacheNodes)
+	}
+	return finder, nil
+}
+
+// buildCache decodes up to n nodes in breadth first order from the root.
+// The cache is never changed afterwards, so it can be read by many
+// goroutines at once.
+func (d *dawg) buildCache(n int) {
+	r := newBitSeeker(d.r)
+	cache := make(map[int64]*cachedNode)
+	queue := []int64{d.firstNodeOffset}
+	for len(queue) > 0 && len(cache) < n {
+		pos := queue[0]
+		queue = queue[1:]
+		if _, ok := cache[pos]; ok {
+			continue
+		}
+
+		node := &cachedNode{nodeResult: d.getNode(&r, pos)}
+		for _, edge := range node.edges {
+			r.Seek(edge.node, 0)
+			node.finals = append(node.finals, r.ReadBits(1) == 1)
+			queue = append(queue, edge.node)
+		}
+		cache[pos] = node
+	}
+
+	d.cache = cache
+}
+
+// cachedNode returns the node from the cache, or nil if it is not there.
+// The lookup is counted in r, and added to the totals by addCacheStats.
+func (d *dawg) cachedNode(r *bitSeeker, node int64) *cachedNode {
+	if d.cache == nil {
+		return nil
+	}
+
+	if node == rootNode {
+		node = d.firstNodeOffset
+	}
+
+	cached := d.cache[node]
+	if cached != nil {
+		r.cacheHits++
+	} else {
+		r.cacheMisses++
+	}
+	return cached
+}
+
+// addCacheStats adds the cache lookups counted in r to the totals. Queries
+// call it once when they finish, rather than updating the shared totals at
+// every node, which would make concurrent queries contend for them.
+func (d *dawg) addCacheStats(r *bitSeeker) {
+	if r.cacheHits != 0 {
+		atomic.AddInt64(&d.cacheHits, r.cacheHits)
+		r.cacheHits = 0
+	}
+	if r.cacheMisses != 0 {
+		atomic.AddInt64(&d.cacheMisses, r.cacheMisses)
+		r.cacheMisses = 0
+	}
+}
+
+// getEdge is getEdge for a node in the cache
+func (c *cachedNode) getEdge(ch rune) (edgeEnd, bool, bool) {
+	i := c.search(ch)
+	if i == len(c.edges) || c.edges[i].ch != ch {
+		return edgeEnd{}, false, false
+	}
+
+	edge := c.edges[i]
+	return edgeEnd{node: edge.node, count: edge.count}, c.finals[i], true
+}
+
+// getEdgeSpan is getEdgeSpan for a node in the cache
+func (c *cachedNode) getEdgeSpan(total int, ch rune) edgeSpan {
+	var span edgeSpan
+	i := c.search(ch)
+	span.before = total
+	if i < len(c.edges) {
+		span.before = c.edges[i].count
+	}
+
+	span.through = span.before
+	if i < len(c.edges) && c.edges[i].ch == ch {
+		span.through = total
+		if i+1 < len(c.edges) {
+			span.through = c.edges[i+1].count
+		}
+		span.node = c.edges[i].node
+		span.final = c.finals[i]
+		span.ok = true
+	}
+	return span
+}
+
+// search returns the index of the first edge that is not less than ch
+func (c *cachedNode) search(ch rune) int {
+	return sort.Search(len(c.edges), func(i int) bool {
+		return c.edges[i].ch >= ch
+	})
+}
+
+// CacheStats returns the number of nodes in the cache and how often it was
+// used.
+func (d *dawg) CacheStats() CacheStats {
+	return CacheStats{
+		Nodes:  len(d.cache),
+		Hits:   atomic.LoadInt64(&d.cacheHits),
+		Misses: atomic.LoadInt64(&d.cacheMisses),
+	}
+}
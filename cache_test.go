@@ -0,0 +1,70 @@
+package dawg_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/smhanov/dawg"
+)
+
+func TestCacheNodes(t *testing.T) {
+	words := numberWords(1000)
+	filename := filepath.Join(t.TempDir(), "test.dawg")
+	if _, err := createDawg(words).Save(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	finder, err := dawg.LoadWithOptions(filename, dawg.LoadOptions{CacheNodes: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer finder.Close()
+
+	for i, word := range words {
+		if index := finder.IndexOf(word); index != i {
+			t.Errorf("IndexOf(%q) returned %d", word, index)
+		}
+
+		if result, ok := finder.Ceiling(word + "0"); i+1 < len(words) && (!ok || result.Index != i+1) {
+			t.Errorf("Ceiling(%q) returned %v", word+"0", result)
+		}
+
+		if found, err := finder.AtIndex(i); err != nil || found != word {
+			t.Errorf("AtIndex(%d) returned %q, %v", i, found, err)
+		}
+	}
+
+	stats := finder.CacheStats()
+	if stats.Nodes != 3 || stats.Hits == 0 || stats.Misses == 0 {
+		t.Errorf("CacheStats returned %+v", stats)
+	}
+
+	uncached := loadDawg(t, words)
+	defer uncached.Close()
+	if stats := uncached.CacheStats(); stats != (dawg.CacheStats{}) {
+		t.Errorf("CacheStats without a cache returned %+v", stats)
+	}
+}
+
+func BenchmarkParallelIndexOfCached(b *testing.B) {
+	words := numberWords(100000)
+	filename := filepath.Join(b.TempDir(), "test.dawg")
+	if _, err := createDawg(words).Save(filename); err != nil {
+		b.Fatal(err)
+	}
+
+	finder, err := dawg.LoadWithOptions(filename, dawg.LoadOptions{CacheNodes: 1000})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer finder.Close()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			finder.IndexOf(words[i%len(words)])
+			i += 7919
+		}
+	})
+}
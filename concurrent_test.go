@@ -0,0 +1,171 @@
+package dawg_test
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/smhanov/dawg"
+)
+
+// numberWords returns n distinct words in alphabetical order
+func numberWords(n int) []string {
+	words := make([]string, n)
+	for i := range words {
+		words[i] = fmt.Sprintf("w%07d", i)
+	}
+	return words
+}
+
+func loadDawg(t testing.TB, words []string) dawg.Finder {
+	filename := filepath.Join(t.TempDir(), "test.dawg")
+	if _, err := createDawg(words).Save(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	finder, err := dawg.Load(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return finder
+}
+
+func TestConcurrentQueries(t *testing.T) {
+	words := numberWords(1000)
+	finder := loadDawg(t, words)
+	defer finder.Close()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := g; i < len(words); i += 8 {
+				if index := finder.IndexOf(words[i]); index != i {
+					t.Errorf("IndexOf(%q) returned %d", words[i], index)
+				}
+
+				if word, err := finder.AtIndex(i); err != nil || word != words[i] {
+					t.Errorf("AtIndex(%d) returned %q, %v", i, word, err)
+				}
+			}
+
+			count := 0
+			finder.Enumerate(func(index int, word []rune, final bool) dawg.EnumerationResult {
+				if final {
+					count++
+				}
+				return dawg.Continue
+			})
+
+			if count != len(words) {
+				t.Errorf("Enumerate found %d words", count)
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func TestCloseWhileQuerying(t *testing.T) {
+	words := numberWords(1000)
+	finder := loadDawg(t, words)
+
+	var wg sync.WaitGroup
+	started := make(chan struct{})
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i = (i + 1) % len(words) {
+				if i == 0 {
+					select {
+					case started <- struct{}{}:
+					default:
+					}
+				}
+
+				word, err := finder.AtIndex(i)
+				if errors.Is(err, dawg.ErrClosed) {
+					return
+				} else if err != nil || word != words[i] {
+					t.Errorf("AtIndex(%d) returned %q, %v", i, word, err)
+					return
+				}
+			}
+		}()
+	}
+
+	<-started
+	if err := finder.Close(); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	if err := finder.Close(); !errors.Is(err, dawg.ErrClosed) {
+		t.Errorf("second Close returned %v", err)
+	}
+
+	// queries which cannot return an error find nothing
+	if index := finder.IndexOf(words[0]); index != -1 {
+		t.Errorf("IndexOf after Close returned %d", index)
+	}
+	if _, ok := finder.Value(words[0]); ok {
+		t.Errorf("Value after Close found the word")
+	}
+	finder.Enumerate(func(index int, word []rune, final bool) dawg.EnumerationResult {
+		t.Errorf("Enumerate after Close found %q", string(word))
+		return dawg.Stop
+	})
+	if it := finder.WithPrefix(""); it.Next() {
+		t.Errorf("Next after Close found %q", it.Word())
+	}
+	if _, err := finder.AtIndex(0); !errors.Is(err, dawg.ErrClosed) {
+		t.Errorf("AtIndex after Close returned %v", err)
+	}
+}
+
+func BenchmarkParallelIndexOf(b *testing.B) {
+	words := numberWords(100000)
+	finder := loadDawg(b, words)
+	defer finder.Close()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			finder.IndexOf(words[i%len(words)])
+			i += 7919
+		}
+	})
+}
+
+func BenchmarkParallelAtIndex(b *testing.B) {
+	words := numberWords(100000)
+	finder := loadDawg(b, words)
+	defer finder.Close()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			finder.AtIndex(i % len(words))
+			i += 7919
+		}
+	})
+}
+
+func BenchmarkParallelEnumerate(b *testing.B) {
+	finder := loadDawg(b, numberWords(1000))
+	defer finder.Close()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			finder.Enumerate(func(index int, word []rune, final bool) dawg.EnumerationResult {
+				return dawg.Continue
+			})
+		}
+	})
+}
@@ -1,12 +1,17 @@
 package dawg
 
 import (
+	"bufio"
 	"bytes"
 	"errors"
 	"fmt"
 	"io"
-	"log"
-	"strconv"
+	"iter"
+	"os"
+	"regexp/syntax"
+	"slices"
+	"sort"
+	"sync"
 )
 
 // FindResult is the result of a lookup in the d. It
@@ -15,6 +20,10 @@ import (
 type FindResult struct {
 	Word  string
 	Index int
+
+	// For approximate searches, the number of edits needed to
+	// change the input into the word.
+	Distance int
 }
 
 type edgeStart struct {
@@ -27,7 +36,7 @@ func (edge edgeStart) String() string {
 }
 
 type edgeEnd struct {
-	node  int
+	node  int64
 	count int
 }
 
@@ -63,14 +72,81 @@ type Finder interface {
 	// Find all prefixes of the given string
 	FindAllPrefixesOf(input string) []FindResult
 
+	// Find all words that occur anywhere in the text
+	FindAllOccurrences(text string) []Match
+
 	// Find the index of the given string
 	IndexOf(input string) int
 
+	// Find the index of the given key in a dawg of bytes
+	IndexOfBytes(input []byte) int
+
+	// Find the index of each of the words, storing them in out
+	IndexOfBatch(words []string, out []int)
+
+	// Find all words within the given number of insertions, deletions and
+	// substitutions of the input
+	FindWithinDistance(input string, maxEdits int) []FindResult
+
+	// Find all words within the given number of insertions, deletions,
+	// substitutions and transpositions of the input
+	FindWithinDamerauDistance(input string, maxEdits int) []FindResult
+
+	// Find all words that can be spelled with the letters
+	Anagrams(letters string, opts AnagramOptions) []FindResult
+
+	// Find the value stored with the given string. The second result is
+	// false if the string is not in the dawg.
+	Value(input string) (uint64, bool)
+
 	AtIndex(index int) (string, error)
 
+	// Find the key with the given index in a dawg of bytes
+	AtIndexBytes(index int) ([]byte, error)
+
 	// Enumerate all prefixes stored in the dawg.
 	Enumerate(fn EnumFn)
 
+	// Call fn for each word that matches a pattern containing wildcards
+	// such as '?', '*' and [a-z]
+	Match(pattern string, fn EnumFn)
+
+	// Call fn for each word that the regular expression matches in full
+	MatchRegexp(re *syntax.Regexp, fn EnumFn) error
+
+	// Iterate over all words that start with the prefix
+	WithPrefix(prefix string) Iterator
+
+	// Iterate over all words, for use with range
+	All() iter.Seq2[int, string]
+
+	// Iterate over all prefixes of the words, for use with range
+	Prefixes() iter.Seq[Prefix]
+
+	// Iterate over the words from "from" up to but not including "to"
+	Range(from, to string) iter.Seq2[int, string]
+
+	// Find the range of indexes of words that start with the prefix
+	PrefixRange(prefix string) (lo, hi int, ok bool)
+
+	// Count the words that start with the prefix
+	CountWithPrefix(prefix string) int
+
+	// Find the number of words that sort before s
+	Rank(s string) int
+
+	// Find the first word that is greater than or equal to the word
+	Ceiling(word string) (FindResult, bool)
+
+	// Find the last word that is less than or equal to the word
+	Floor(word string) (FindResult, bool)
+
+	// Find the first word that is greater than the word
+	Next(word string) (FindResult, bool)
+
+	// Find the last word that is less than the word
+	Prev(word string) (FindResult, bool)
+
 	// Returns the number of words
 	NumAdded() int
 
@@ -80,16 +156,23 @@ type Finder interface {
 	// Returns the number of nodes
 	NumNodes() int
 
+	// Returns the number of nodes in the cache and how often it was used
+	CacheStats() CacheStats
+
 	// Output a human-readable description of the dawg to stdout
 	Print()
 
-	// Close the dawg that was opened with Load(). After this, it is no longer
-	// accessible.
+	// Close the dawg that was opened with Load(), after waiting for queries
+	// in progress. After this, it is no longer accessible. It must not be
+	// called from inside the callback of a query, which would wait forever.
 	Close() error
 
 	// Save to a writer
 	Write(w io.Writer) (int64, error)
 
+	// Save to a writer, using the given layout
+	WriteWithOptions(w io.Writer, opts WriteOptions) (int64, error)
+
 	// Save to a file
 	Save(filename string) (int64, error)
 }
@@ -99,11 +182,43 @@ type Builder interface {
 	// Add the word to the dawg
 	Add(wordIn string)
 
+	// Add the word to the dawg, storing a value with it
+	AddWithValue(wordIn string, value uint64)
+
+	// Add the word to the dawg, returning an error instead of panicking
+	TryAdd(wordIn string) error
+
+	// Add the word and value to the dawg, returning an error instead of
+	// panicking
+	TryAddWithValue(wordIn string, value uint64) error
+
+	// Add a key of arbitrary bytes to the dawg
+	AddBytes(word []byte)
+
+	// Add a key of arbitrary bytes to the dawg, returning an error instead
+	// of panicking
+	TryAddBytes(word []byte) error
+
 	// Returns true if the word can be added.
 	CanAdd(word string) bool
 
-	// Complete the dawg and return a Finder.
+	// Complete the dawg and return a Finder. It cannot be used after
+	// FinishTo, which does not keep the dawg in memory.
 	Finish() Finder
+
+	// Complete the dawg and return a Finder, returning an error instead of
+	// panicking. After FinishTo, it returns ErrStreamed.
+	FinishE() (Finder, error)
+
+	// Complete the dawg and write it to w, without keeping it in memory
+	FinishTo(w io.Writer) (int64, error)
+
+	// Complete the dawg and save it to a file, without keeping it in memory
+	FinishToFile(filename string) (int64, error)
+
+	// Discard the words added so far, and remove any temporary files. It
+	// does nothing if the dawg is finished.
+	Abort() error
 }
 
 const rootNode = 0
@@ -118,15 +233,28 @@ type node struct {
 type dawg struct {
 	// these are erased after we finish building
 	lastWord       []rune
-	nextID         int
 	uncheckedNodes []uncheckedNode
-	minimizedNodes map[string]int
-	nodes          map[int]*node
+	register       register
+	nodes          []node
+	freeNodes      []int // ids of nodes that were replaced, to be reused
+	values         []uint64
+	aborted        bool
+	streamed       bool // finished by FinishTo, so nothing is kept in memory
 
 	// if read from a file, this is set
 	r    io.ReaderAt
 	size int64 // size of the readerAt
 
+	// number of queries in progress, with closedBit set by Close
+	users    int64
+	idle     chan struct{} // closed when the last query finishes after Close
+	idleOnce sync.Once
+
+	// decoded nodes, if LoadOptions.CacheNodes was used
+	cache       map[int64]*cachedNode
+	cacheHits   int64
+	cacheMisses int64
+
 	// these are kept
 	finished        bool
 	numAdded        int
@@ -135,18 +263,24 @@ type dawg struct {
 	cbits           int64 // bits to represent character value
 	abits           int64 // bits to represent node address
 	wbits           int64 // bits to represent number of words / counts
+	aligned         bool  // the file uses the aligned layout
 	firstNodeOffset int64 // first node offset in bits in the file
 	hasEmptyWord    bool
+	hasValues       bool
+	byteKeys        bool   // words are sequences of bytes rather than runes
+	alphabet        []rune // characters of the edges, if the file has an alphabet
+	vbits           int64  // bits to represent each value
+	valuesOffset    int64  // offset in bytes of the value table in the file
+
+	// the memory mapped file, from which the aligned layout is read in place
+	data []byte
 }
 
 // New creates a new dawg
 func New() Builder {
 	return &dawg{
-		nextID:         1,
-		minimizedNodes: make(map[string]int),
-		nodes: map[int]*node{
-			0: {count: -1},
-		},
+		idle:  make(chan struct{}),
+		nodes: []node{{count: -1}},
 	}
 }
 
@@ -154,20 +288,30 @@ func New() Builder {
 // Words must be added in alphabetical order.
 func (d *dawg) CanAdd(word string) bool {
 	return !d.finished &&
-		(d.numAdded == 0 || word > string(d.lastWord))
+		(d.numAdded == 0 || slices.Compare(d.letters(word), d.lastWord) > 0)
 }
 
 // Add adds a word to the structure.
 // Adding a word not in alphaetical order, or to a finished dawg will panic.
 func (d *dawg) Add(wordIn string) {
-	if d.numAdded > 0 && wordIn <= string(d.lastWord) {
-		log.Printf("Last word=%s newword=%s", string(d.lastWord), wordIn)
-		panic(errors.New("d.AddWord(): Words not in alphabetical order"))
-	} else if d.finished {
-		panic(errors.New("d.AddWord(): Tried to add to a finished dawg"))
+	if err := d.TryAdd(wordIn); err != nil {
+		panic(err)
 	}
+}
+
+// TryAdd adds a word to the structure. It returns ErrOutOfOrder if the word
+// is not in alphabetical order, or ErrFinished if the dawg is finished.
+func (d *dawg) TryAdd(wordIn string) error {
+	return d.add(d.letters(wordIn))
+}
 
-	word := []rune(wordIn)
+// add adds a word, given as the letters of its edges.
+func (d *dawg) add(word []rune) error {
+	if d.finished {
+		return ErrFinished
+	} else if d.numAdded > 0 && slices.Compare(word, d.lastWord) <= 0 {
+		return ErrOutOfOrder
+	}
 
 	// find common prefix between word and previous word
 	commonPrefix := 0
@@ -202,37 +346,152 @@ func (d *dawg) Add(wordIn string) {
 	d.setFinal(node)
 	d.lastWord = word
 	d.numAdded++
+	return nil
+}
+
+// AddWithValue adds a word to the structure, along with a value that can be
+// retrieved using Finder.Value(). Words added using Add() will have a value of
+// zero. It will panic under the same conditions as Add.
+func (d *dawg) AddWithValue(wordIn string, value uint64) {
+	if err := d.TryAddWithValue(wordIn, value); err != nil {
+		panic(err)
+	}
+}
+
+// TryAddWithValue adds a word and its value to the structure. It returns an
+// error under the same conditions as TryAdd.
+func (d *dawg) TryAddWithValue(wordIn string, value uint64) error {
+	if err := d.TryAdd(wordIn); err != nil {
+		return err
+	}
+	for len(d.values) < d.numAdded-1 {
+		d.values = append(d.values, 0)
+	}
+	d.values = append(d.values, value)
+	return nil
 }
 
 // Finish will mark the dawg as complete. The dawg cannot be used for lookups
-// until Finish has been called.
+// until Finish has been called. It panics with ErrStreamed if the dawg was
+// already finished using FinishTo.
 func (d *dawg) Finish() Finder {
+	finder, err := d.FinishE()
+	if err != nil {
+		panic(err)
+	}
+	return finder
+}
+
+// FinishE will mark the dawg as complete and return a Finder, or an error if
+// the dawg could not be encoded. It returns ErrStreamed if the dawg was
+// already finished using FinishTo, since it was not kept in memory.
+func (d *dawg) FinishE() (Finder, error) {
 	if !d.finished {
-		d.finished = true
+		d.complete()
 
-		d.minimize(0)
+		var buffer bytes.Buffer
+		size, err := d.Write(&buffer)
+		d.nodes = nil
+		d.values = nil
+		if err != nil {
+			return nil, err
+		}
+		d.size = size
+		d.r = bytes.NewReader(buffer.Bytes())
+	}
 
-		d.numNodes = len(d.minimizedNodes) + 1
+	if d.aborted {
+		return nil, ErrAborted
+	} else if d.streamed {
+		return nil, ErrStreamed
+	} else if d.r == nil {
+		return nil, errors.New("dawg: could not be encoded")
+	}
 
-		// Fill in the counts
-		d.calculateSkipped(rootNode)
+	return Read(d.r, 0)
+}
+
+// FinishTo will mark the dawg as complete and write it to w, returning the
+// number of bytes written. Unlike Finish, the encoded dawg is not kept in
+// memory, so it cannot be searched until it is read again with Read or
+// Load. If the dawg was already finished using Finish, it is written again.
+func (d *dawg) FinishTo(w io.Writer) (int64, error) {
+	if d.finished {
+		if d.aborted {
+			return 0, ErrAborted
+		} else if d.streamed {
+			return 0, ErrStreamed
+		} else if d.r == nil {
+			return 0, errors.New("dawg: could not be encoded")
+		}
+		return d.Write(w)
+	}
 
-		// no longer need the names.
-		d.uncheckedNodes = nil
-		d.minimizedNodes = nil
-		d.lastWord = nil
+	d.complete()
+	d.streamed = true
+	buffered := bufio.NewWriter(w)
+	size, err := d.encode(buffered, false)
+	d.nodes = nil
+	d.values = nil
+	if err != nil {
+		return 0, err
+	}
 
-		d.renumber()
+	return size, buffered.Flush()
+}
 
-		var buffer bytes.Buffer
-		d.size, _ = d.Write(&buffer)
-		d.r = bytes.NewReader(buffer.Bytes())
-		d.nodes = nil
+// FinishToFile will mark the dawg as complete and save it to a file, like
+// FinishTo.
+func (d *dawg) FinishToFile(filename string) (int64, error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return 0, err
 	}
 
-	finder, _ := Read(d.r, 0)
+	size, err := d.FinishTo(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return size, err
+}
 
-	return finder
+// Abort discards the words added so far. Afterward, words cannot be added,
+// and Finish fails with ErrAborted. It does nothing if the dawg is finished.
+func (d *dawg) Abort() error {
+	if d.finished {
+		return nil
+	}
+
+	d.finished = true
+	d.aborted = true
+	d.lastWord = nil
+	d.uncheckedNodes = nil
+	d.register = register{}
+	d.nodes = nil
+	d.freeNodes = nil
+	d.values = nil
+	return nil
+}
+
+// complete minimizes the rest of the graph and numbers the nodes, so that
+// the dawg can be encoded.
+func (d *dawg) complete() {
+	d.finished = true
+
+	d.minimize(0)
+
+	d.numNodes = d.register.count + 1
+
+	// Fill in the counts
+	d.calculateSkipped(rootNode)
+
+	// no longer need the register.
+	d.uncheckedNodes = nil
+	d.register = register{}
+	d.freeNodes = nil
+	d.lastWord = nil
+
+	d.renumber()
 }
 
 func (d *dawg) renumber() {
@@ -241,36 +500,57 @@ func (d *dawg) renumber() {
 	// process them in a depth-first order so that runs of characters
 	// will appear in consecutive nodes, which is more efficient for encoding.
 
-	remap := make(map[int]int)
+	remap := make([]int, len(d.nodes))
+	for i := range remap {
+		remap[i] = -1
+	}
 
-	var process func(id int)
+	// each entry on the stack is a node and the next of its edges to follow
+	type frame struct {
+		id, edge int
+	}
 
-	process = func(id int) {
-		if _, ok := remap[id]; ok {
-			return
+	remap[rootNode] = 0
+	numNodes := 1
+	stack := []frame{{rootNode, 0}}
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		edges := d.nodes[top.id].edges
+		if top.edge == len(edges) {
+			stack = stack[:len(stack)-1]
+			continue
 		}
 
-		remap[id] = len(remap)
-		node := d.nodes[id]
-		for _, edge := range node.edges {
-			process(edge.node)
+		child := edges[top.edge].node
+		top.edge++
+		if remap[child] < 0 {
+			remap[child] = numNodes
+			numNodes++
+			stack = append(stack, frame{child, 0})
 		}
 	}
 
-	process(rootNode)
-
-	nodes := make(map[int]*node)
+	nodes := make([]node, numNodes)
 	for id, node := range d.nodes {
-		nodes[remap[id]] = node
+		if remap[id] < 0 {
+			// the node was replaced
+			continue
+		}
+
 		for i := range node.edges {
 			node.edges[i].node = remap[node.edges[i].node]
 		}
+		nodes[remap[id]] = node
 	}
 	d.nodes = nodes
 }
 
 // Print will print all edges to the standard output
 func (d *dawg) Print() {
+	if !d.begin() {
+		return
+	}
+	defer d.release()
 	DumpFile(d.r)
 }
 
@@ -279,18 +559,23 @@ func (d *dawg) Print() {
 func (d *dawg) FindAllPrefixesOf(input string) []FindResult {
 
 	d.checkFinished()
+	if !d.begin() {
+		return nil
+	}
+	defer d.release()
 
 	var results []FindResult
 	skipped := 0
 	final := d.hasEmptyWord
-	node := rootNode
+	node := int64(rootNode)
 	var edgeEnd edgeEnd
 	var ok bool
 
 	r := newBitSeeker(d.r)
+	defer d.addCacheStats(&r)
 
 	// for each character of the input
-	for pos, letter := range input {
+	for pos := 0; pos < len(input); {
 		// if the node is final, add a result
 		if final {
 			results = append(results, FindResult{
@@ -299,8 +584,11 @@ func (d *dawg) FindAllPrefixesOf(input string) []FindResult {
 			})
 		}
 
+		letter, size := d.nextLetter(input[pos:])
+		pos += size
+
 		// check if there is an outgoing edge for the letter
-		edgeEnd, final, ok = d.getEdge(&r, edgeStart{node: node, ch: letter})
+		edgeEnd, final, ok = d.getEdge(&r, node, letter)
 		if !ok {
 			return results
 		}
@@ -324,17 +612,35 @@ func (d *dawg) FindAllPrefixesOf(input string) []FindResult {
 // If the item was never inserted, it returns -1
 // It will panic if the dawg is not finished.
 func (d *dawg) IndexOf(input string) int {
+	if !d.begin() {
+		return -1
+	}
+	defer d.release()
+	return d.indexOf(input)
+}
+
+func (d *dawg) indexOf(input string) int {
+	if d.data != nil && d.cache == nil {
+		if index, ok := d.indexOfMapped(input); ok {
+			return index
+		}
+	}
+
 	skipped := 0
-	node := rootNode
+	node := int64(rootNode)
 	final := d.hasEmptyWord
 	var ok bool
 	var edgeEnd edgeEnd
 	r := newBitSeeker(d.r)
+	defer d.addCacheStats(&r)
 
 	// for each character of the input
-	for _, letter := range input {
+	for pos := 0; pos < len(input); {
+		letter, size := d.nextLetter(input[pos:])
+		pos += size
+
 		// check if there is an outgoing edge for the letter
-		edgeEnd, final, ok = d.getEdge(&r, edgeStart{node: node, ch: letter})
+		edgeEnd, final, ok = d.getEdge(&r, node, letter)
 		//log.Printf("Follow %v:%v=>%v (ok=%v)", node, string(letter), edgeEnd.node, ok)
 		if !ok {
 			// not found
@@ -353,6 +659,94 @@ func (d *dawg) IndexOf(input string) int {
 	return -1
 }
 
+// IndexOfBatch stores the index of each of the words in the same position of
+// out, or -1 if the word is not in the dawg. Out must be at least as long as
+// words. The words are looked up in alphabetical order, so that the part of
+// the path shared with the previous word does not need to be followed again.
+func (d *dawg) IndexOfBatch(words []string, out []int) {
+	if !d.begin() {
+		for i := range words {
+			out[i] = -1
+		}
+		return
+	}
+	defer d.release()
+
+	type step struct {
+		ch      rune
+		node    int64
+		skipped int
+		final   bool
+	}
+
+	order := make([]int, len(words))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return words[order[i]] < words[order[j]]
+	})
+
+	r := newBitSeeker(d.r)
+	defer d.addCacheStats(&r)
+
+	// path[i] is the state after following i+1 letters of the last word
+	var path []step
+	for _, i := range order {
+		runes := d.letters(words[i])
+		shared := 0
+		for shared < len(path) && shared < len(runes) && path[shared].ch == runes[shared] {
+			shared++
+		}
+		path = path[:shared]
+
+		current := step{node: rootNode, final: d.hasEmptyWord}
+		if shared > 0 {
+			current = path[shared-1]
+		}
+
+		out[i] = -1
+		found := true
+		for _, letter := range runes[shared:] {
+			edgeEnd, final, ok := d.getEdge(&r, current.node, letter)
+			if !ok {
+				found = false
+				break
+			}
+
+			current = step{letter, edgeEnd.node, current.skipped + edgeEnd.count, final}
+			path = append(path, current)
+		}
+
+		if found && current.final {
+			out[i] = current.skipped
+		}
+	}
+}
+
+// Value returns the value that was stored with the word using AddWithValue.
+// If the word was added without a value, the value is zero. The second result
+// is false if the word is not in the dawg.
+func (d *dawg) Value(input string) (uint64, bool) {
+	if !d.begin() {
+		return 0, false
+	}
+	defer d.release()
+
+	index := d.indexOf(input)
+	if index < 0 {
+		return 0, false
+	}
+
+	if !d.hasValues || d.vbits == 0 {
+		return 0, true
+	}
+
+	r := newBitSeeker(d.r)
+	r.Seek(d.valuesOffset*8+int64(index)*d.vbits, 0)
+	return r.ReadBits(d.vbits), true
+}
+
 // NumAdded returns the number of words added
 func (d *dawg) NumAdded() int {
 	return d.numAdded
@@ -379,13 +773,9 @@ func (d *dawg) minimize(downTo int) {
 	// proceed from the leaf up to a certain point
 	for i := len(d.uncheckedNodes) - 1; i >= downTo; i-- {
 		u := d.uncheckedNodes[i]
-		name := d.nameOf(u.child)
-		if node, ok := d.minimizedNodes[name]; ok {
+		if node := d.findOrAdd(u.child); node != u.child {
 			// replace the child with the previously encountered one
 			d.replaceChild(u.parent, u.ch, node)
-		} else {
-			// add the state to the minimized nodes.
-			d.minimizedNodes[name] = u.child
 		}
 	}
 
@@ -393,27 +783,15 @@ func (d *dawg) minimize(downTo int) {
 }
 
 func (d *dawg) newNode() int {
-	d.nextID++
-	return d.nextID - 1
-}
-
-func (d *dawg) nameOf(nodeid int) string {
-	node := d.nodes[nodeid]
-
-	// node name is id_ch:id... for each child
-	buff := bytes.Buffer{}
-	for _, edge := range node.edges {
-		buff.WriteByte('_')
-		buff.WriteRune(edge.ch)
-		buff.WriteByte(':')
-		buff.WriteString(strconv.Itoa(edge.node))
+	if n := len(d.freeNodes); n > 0 {
+		id := d.freeNodes[n-1]
+		d.freeNodes = d.freeNodes[:n-1]
+		d.nodes[id] = node{count: -1}
+		return id
 	}
 
-	if node.final {
-		buff.WriteByte('!')
-	}
-
-	return buff.String()
+	d.nodes = append(d.nodes, node{count: -1})
+	return len(d.nodes) - 1
 }
 
 func (d *dawg) setFinal(node int) {
@@ -426,20 +804,15 @@ func (d *dawg) setFinal(node int) {
 func (d *dawg) addChild(parent int, ch rune, child int) {
 	//log.Printf("Addchild %v(%v)->%v", parent, string(ch), child)
 	d.numEdges++
-	if d.nodes[child] == nil {
-		d.nodes[child] = &node{
-			count: -1,
-		}
-	}
-	node := d.nodes[parent]
+	node := &d.nodes[parent]
 	if len(node.edges) > 0 && ch <= node.edges[len(node.edges)-1].ch {
-		log.Panic("Not strictly increasing")
+		panic(ErrOutOfOrder)
 	}
 	node.edges = append(node.edges, edgeStart{child, ch})
 }
 
 func (d *dawg) replaceChild(parent int, ch rune, child int) {
-	pnode := d.nodes[parent]
+	pnode := &d.nodes[parent]
 	//TODO: should be bsearch
 	i := bsearch(len(pnode.edges), func(i int) int {
 		return int(pnode.edges[i].ch - ch)
@@ -449,14 +822,17 @@ func (d *dawg) replaceChild(parent int, ch rune, child int) {
 		//for _, edge := range pnode.edges {
 		//	log.Printf("Edge %c %d", rune(edge.ch), edge.node)
 		//}
-		log.Panicf("Not found: %c", ch)
+		panic(fmt.Errorf("dawg: edge not found: %c", ch))
 	}
 
 	//log.Printf("ReplaceChild(%v:%v=>%v, %v:%v=>%v)",
 	//	parent, string(ch), pnode.edges[i].node,
 	//	parent, string(ch), child)
 
-	delete(d.nodes, pnode.edges[i].node)
+	// the old child is only used by this edge, so it can be reused.
+	old := pnode.edges[i].node
+	d.nodes[old] = node{}
+	d.freeNodes = append(d.freeNodes, old)
 	pnode.edges[i].node = child
 
 }
@@ -467,60 +843,104 @@ func (d *dawg) calculateSkipped(nodeid int) int {
 	// sum of all skipped-over counts of its previous siblings.
 
 	// returns the number of leaves reachable from the node.
-	node := d.nodes[nodeid]
-	if node.count >= 0 {
-		return node.count
+	if d.nodes[nodeid].count >= 0 {
+		return d.nodes[nodeid].count
 	}
 
-	numReachable := 0
-
-	if node.final {
-		numReachable++
+	// each entry on the stack is a node, the next of its edges to follow,
+	// and the number of leaves reachable through the edges before it.
+	type frame struct {
+		id, edge, reachable int
 	}
 
-	for _, edge := range node.edges {
-		numReachable += d.calculateSkipped(edge.node)
-	}
+	stack := []frame{{nodeid, 0, 0}}
+	for {
+		top := &stack[len(stack)-1]
+		node := &d.nodes[top.id]
+		if top.edge < len(node.edges) {
+			child := node.edges[top.edge].node
+			if count := d.nodes[child].count; count >= 0 {
+				top.reachable += count
+				top.edge++
+			} else {
+				stack = append(stack, frame{child, 0, 0})
+			}
+			continue
+		}
+
+		numReachable := top.reachable
+		if node.final {
+			numReachable++
+		}
+		node.count = numReachable
 
-	node.count = numReachable
+		stack = stack[:len(stack)-1]
+		if len(stack) == 0 {
+			return numReachable
+		}
 
-	return numReachable
+		parent := &stack[len(stack)-1]
+		parent.reachable += numReachable
+		parent.edge++
+	}
 }
 
 // Enumerate will call the given method, passing it every possible prefix of words in the index.
 // Return Continue to continue enumeration, Skip to skip this branch, or Stop to stop enumeration.
 func (d *dawg) Enumerate(fn EnumFn) {
+	if !d.begin() {
+		return
+	}
+	defer d.release()
+
 	r := newBitSeeker(d.r)
+	defer d.addCacheStats(&r)
 	d.enumerate(&r, 0, rootNode, nil, fn)
 }
 
-func (d *dawg) enumerate(r *bitSeeker, index int, address int, runes []rune, fn EnumFn) EnumerationResult {
-	// get the node and whether its final
-	node := d.getNode(r, address)
-
-	// call the enum function on the runes
-	result := fn(index, runes, node.final)
-
-	// if the function didn't say to continue, then return.
-	if result != Continue {
-		return result
+func (d *dawg) enumerate(r *bitSeeker, index int, address int64, runes []rune, fn EnumFn) EnumerationResult {
+	// each entry on the stack is a node whose edges are being followed,
+	// with the index of the node and the next edge to follow. The runes
+	// always hold the path to the top of the stack.
+	type frame struct {
+		edges []edgeResult
+		index int
+		edge  int
 	}
 
-	l := len(runes)
-	runes = append(runes, 0)
+	var stack []frame
+	depth := len(runes)
+	for {
+		// get the node and whether its final
+		node := d.getNode(r, address)
 
-	// for each edge
-	for _, edge := range node.edges {
-		// add ch to the runes
-		runes[l] = edge.ch
-		// recurse
-		result = d.enumerate(r, index+edge.count, edge.node, runes, fn)
+		// call the enum function on the runes, and if it said to continue,
+		// follow the edges of the node.
+		result := fn(index, runes, node.final)
 		if result == Stop {
-			break
+			return Stop
+		} else if result == Continue && len(node.edges) > 0 {
+			stack = append(stack, frame{node.edges, index, 0})
+			runes = append(runes, 0)
+		}
+
+		// find the next edge to follow, from the deepest node that has one
+		for len(stack) > 0 && stack[len(stack)-1].edge == len(stack[len(stack)-1].edges) {
+			stack = stack[:len(stack)-1]
+			runes = runes[:len(runes)-1]
+		}
+
+		if len(stack) == 0 {
+			return result
 		}
-	}
 
-	return result
+		top := &stack[len(stack)-1]
+		edge := top.edges[top.edge]
+		top.edge++
+		runes[depth+len(stack)-1] = edge.ch
+		index = top.index + edge.count
+		address = edge.node
+	}
 }
 
 func min(a, b int) int {
@@ -535,35 +955,42 @@ func (d *dawg) AtIndex(index int) (string, error) {
 		return "", errors.New("invalid index")
 	}
 
+	if err := d.acquire(); err != nil {
+		return "", err
+	}
+	defer d.release()
+
 	r := newBitSeeker(d.r)
+	defer d.addCacheStats(&r)
 	// start at first node and empty string
 	result, _ := d.atIndex(&r, rootNode, 0, index, nil)
 	return result, nil
 }
 
-func (d *dawg) atIndex(r *bitSeeker, nodeNumber, atIndex, targetIndex int, runes []rune) (string, bool) {
-	node := d.getNode(r, nodeNumber)
-	// if node is final and index matches, return it
-	if node.final && atIndex == targetIndex {
-		return string(runes), true
-	}
+func (d *dawg) atIndex(r *bitSeeker, nodeNumber int64, atIndex, targetIndex int, runes []rune) (string, bool) {
+	for {
+		node := d.getNode(r, nodeNumber)
+		// if node is final and index matches, return it
+		if node.final && atIndex == targetIndex {
+			return d.wordOf(runes), true
+		}
 
-	next := bsearch(len(node.edges), func(i int) int {
-		return atIndex + node.edges[i].count - targetIndex
-	})
+		// follow the last edge that does not skip past the target
+		next := bsearch(len(node.edges), func(i int) int {
+			return atIndex + node.edges[i].count - targetIndex
+		})
 
-	if next == len(node.edges) || atIndex+node.edges[next].count > targetIndex {
-		next--
-	}
+		if next == len(node.edges) || atIndex+node.edges[next].count > targetIndex {
+			next--
+		}
 
-	//log.Printf("Follow edge %v %c skip=%d", node.edges[next], node.edges[next].ch, node.edges[next].count)
-	runes = append(runes, 0)
-	for i := next; i < len(node.edges); i++ {
-		runes[len(runes)-1] = node.edges[i].ch
-		if result, ok := d.atIndex(r, node.edges[i].node, atIndex+node.edges[i].count, targetIndex, runes); ok {
-			return result, ok
+		if next < 0 {
+			return "", false
 		}
-	}
-	return "", false
 
+		//log.Printf("Follow edge %v %c skip=%d", node.edges[next], node.edges[next].ch, node.edges[next].count)
+		runes = append(runes, node.edges[next].ch)
+		atIndex += node.edges[next].count
+		nodeNumber = node.edges[next].node
+	}
 }
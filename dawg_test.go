@@ -2,10 +2,13 @@ package dawg_test
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"log"
 	"os"
+	"runtime"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/smhanov/dawg"
@@ -241,3 +244,267 @@ func ExampleNew() {
 	// Found prefix cat, index 1
 	// Found prefix cats, index 3
 }
+
+func TestErrors(t *testing.T) {
+	builder := dawg.New()
+	if err := builder.TryAdd("cat"); err != nil {
+		t.Errorf("TryAdd returned %v", err)
+	}
+
+	if err := builder.TryAdd("blip"); err != dawg.ErrOutOfOrder {
+		t.Errorf("TryAdd out of order returned %v", err)
+	}
+
+	if err := builder.TryAdd("cat"); err != dawg.ErrOutOfOrder {
+		t.Errorf("TryAdd repeated word returned %v", err)
+	}
+
+	finder, err := builder.FinishE()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := builder.TryAdd("dog"); err != dawg.ErrFinished {
+		t.Errorf("TryAdd after finish returned %v", err)
+	}
+
+	var buffer bytes.Buffer
+	if _, err := finder.Write(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buffer.Bytes()
+	if _, err := dawg.Read(bytes.NewReader(data[:len(data)-1]), 0); err != dawg.ErrTruncated {
+		t.Errorf("Read of truncated file returned %v", err)
+	}
+
+	if _, err := dawg.Read(bytes.NewReader(data[:2]), 0); err != dawg.ErrTruncated {
+		t.Errorf("Read of truncated header returned %v", err)
+	}
+
+	corrupt := append([]byte{}, data...)
+	corrupt[18] = 0x7f // cbits
+	if _, err := dawg.Read(bytes.NewReader(corrupt), 0); err != dawg.ErrCorrupt {
+		t.Errorf("Read of corrupt file returned %v", err)
+	}
+
+	corrupt[8] = 0x7f // version
+	if _, err := dawg.Read(bytes.NewReader(corrupt), 0); err != dawg.ErrVersion {
+		t.Errorf("Read of newer version returned %v", err)
+	}
+
+	corrupt[4] = 'X' // magic
+	if _, err := dawg.Read(bytes.NewReader(corrupt), 0); err != dawg.ErrCorrupt {
+		t.Errorf("Read with bad magic returned %v", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	var buffer bytes.Buffer
+	if _, err := createDawg([]string{"blip", "cat", "catnip"}).Write(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buffer.Bytes()
+	if err := dawg.Verify(bytes.NewReader(data)); err != nil {
+		t.Errorf("Verify returned %v", err)
+	}
+
+	if err := dawg.Verify(bytes.NewReader(data[:len(data)-1])); err != dawg.ErrTruncated {
+		t.Errorf("Verify of truncated file returned %v", err)
+	}
+
+	// flip each bit after the fixed part of the header
+	for i := 18 * 8; i < len(data)*8; i++ {
+		corrupt := append([]byte{}, data...)
+		corrupt[i/8] ^= 1 << (i % 8)
+		if err := dawg.Verify(bytes.NewReader(corrupt)); err != dawg.ErrCorrupt {
+			t.Errorf("Verify with bit %d flipped returned %v", i, err)
+		}
+	}
+}
+
+func TestReadVersion1(t *testing.T) {
+	// written by an older version of the package.
+	data := []byte{0x0, 0x0, 0x0, 0x1c, 0x7, 0x8, 0x5, 0x8, 0xb, 0x80, 0x5e,
+		0x27, 0x6c, 0x6a, 0x79, 0xd8, 0xe9, 0x78, 0x40, 0x5, 0xc2, 0xf4, 0x80,
+		0x56, 0xe7, 0xfe, 0x74, 0x88}
+
+	finder, err := dawg.Read(bytes.NewReader(data), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testDawg(t, finder, []string{"", "blip", "cat", "catnip", "cats"})
+
+	// it can also be read from the middle of a file
+	finder, err = dawg.Read(bytes.NewReader(append([]byte{1, 2, 3}, data...)), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testDawg(t, finder, []string{"", "blip", "cat", "catnip", "cats"})
+}
+
+func TestIndexOfBatch(t *testing.T) {
+	words := []string{"", "blip", "cat", "catnip", "cats", "dog"}
+	finder := createDawg(words)
+
+	queries := []string{"cats", "ca", "dog", "", "catnip", "catnipper", "blip", "cat", "cats", "z"}
+	out := make([]int, len(queries))
+	finder.IndexOfBatch(queries, out)
+
+	for i, query := range queries {
+		if out[i] != finder.IndexOf(query) {
+			t.Errorf("IndexOfBatch returned %d for %q, expected %d", out[i], query, finder.IndexOf(query))
+		}
+	}
+}
+
+func TestFinishTo(t *testing.T) {
+	words := []string{"", "blip", "cat", "catnip", "cats", "dog"}
+
+	var expected bytes.Buffer
+	if _, err := createDawg(words).Write(&expected); err != nil {
+		t.Fatal(err)
+	}
+
+	builder := dawg.New()
+	for i, word := range words {
+		builder.AddWithValue(word, uint64(i))
+	}
+
+	var buffer bytes.Buffer
+	size, err := builder.FinishTo(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != int64(buffer.Len()) {
+		t.Errorf("FinishTo returned %d, but wrote %d bytes", size, buffer.Len())
+	}
+
+	finder, err := dawg.Read(bytes.NewReader(buffer.Bytes()), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testDawg(t, finder, words)
+	if value, ok := finder.Value("cats"); !ok || value != 4 {
+		t.Errorf("Value(cats) returned %d, %v", value, ok)
+	}
+
+	if _, err := builder.FinishTo(&buffer); err != dawg.ErrStreamed {
+		t.Errorf("FinishTo twice returned %v, expected ErrStreamed", err)
+	}
+	if _, err := builder.FinishE(); err != dawg.ErrStreamed {
+		t.Errorf("FinishE after FinishTo returned %v, expected ErrStreamed", err)
+	}
+
+	// without values, the output is the same as Finish and Write
+	filename := t.TempDir() + "/test.dawg"
+	builder = dawg.New()
+	for _, word := range words {
+		builder.Add(word)
+	}
+	if _, err := builder.FinishToFile(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, expected.Bytes()) {
+		t.Errorf("FinishToFile wrote different bytes than Write")
+	}
+}
+
+func TestLongWords(t *testing.T) {
+	long := strings.Repeat("ACGT", 30000)
+	words := []string{
+		long[:100000],
+		long[:100000] + "A",
+		long[:110000],
+		long[:110000] + "AA",
+		long,
+	}
+
+	builder := dawg.New()
+	for _, word := range words {
+		builder.Add(word)
+	}
+	finder := builder.Finish()
+
+	testDawg(t, finder, words)
+
+	var found []string
+	finder.Enumerate(func(index int, word []rune, final bool) dawg.EnumerationResult {
+		if final {
+			if index != len(found) {
+				t.Errorf("Enumerate gave index %d for word %d", index, len(found))
+			}
+			found = append(found, string(word))
+		}
+		return dawg.Continue
+	})
+
+	if len(found) != len(words) {
+		t.Fatalf("Enumerate found %d words, expected %d", len(found), len(words))
+	}
+	for i, word := range words {
+		if found[i] != word {
+			t.Errorf("Enumerate found a word of length %d, expected %d", len(found[i]), len(word))
+		}
+	}
+
+	if results := finder.FindAllPrefixesOf(long + "G"); len(results) != len(words)-1 {
+		t.Errorf("FindAllPrefixesOf found %d words, expected %d", len(results), len(words)-1)
+	}
+}
+
+// benchmarkWords returns n words in alphabetical order, with shared
+// prefixes and suffixes like those of a real dictionary.
+func benchmarkWords(n int) []string {
+	suffixes := []string{"", "ed", "er", "ing", "s"}
+	var words []string
+	for i := 0; len(words) < n; i++ {
+		stem := fmt.Sprintf("%x", i*2654435761%(1<<32))
+		for _, suffix := range suffixes {
+			words = append(words, stem+suffix)
+		}
+	}
+	sort.Strings(words)
+	return words[:n]
+}
+
+func BenchmarkBuild(b *testing.B) {
+	words := benchmarkWords(200000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		builder := dawg.New()
+		for _, word := range words {
+			builder.Add(word)
+		}
+		builder.Finish()
+	}
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(words)), "ns/word")
+}
+
+func BenchmarkBuildMemory(b *testing.B) {
+	words := benchmarkWords(200000)
+	for i := 0; i < b.N; i++ {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+
+		builder := dawg.New()
+		for _, word := range words {
+			builder.Add(word)
+		}
+
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		b.ReportMetric(float64(int64(after.HeapAlloc)-int64(before.HeapAlloc))/float64(len(words)), "heap-bytes/word")
+		builder.Finish()
+	}
+}
@@ -1,23 +1,42 @@
 package dawg
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
-	"log"
+	"math"
 	"math/bits"
 	"os"
-
-	"golang.org/x/exp/mmap"
+	"sort"
+	"sync/atomic"
 )
 
 /* FILE FORMAT
-- 4 bytes - total size of file
+- 4 bytes: zero. In version 1 files, this was the total size of the file.
+- 4 bytes: "DAWG"
+- 1 byte: format version
+- 8 bytes: total size of file
+- 1 byte: flags
+	- bit 0: the file contains a value table
+	- bit 1: the nodes use the aligned layout, described below
+	- bit 2: each character is a byte of the key rather than a rune
+	- bit 3: the file contains an alphabet
 - 1 byte: cbits
 - 1 byte: abits
+- if the file contains a value table:
+	1 byte: vbits
 - 7code - number of words
 - 7code - number of nodes
 - 7code - number of edges
+- if the file contains an alphabet:
+	7code: number of characters
+	- for each character, in increasing order:
+		7code: the character minus the previous one, or zero for the first
+	The character of each edge is stored as its position in the alphabet,
+	so that cbits depends on the number of characters used rather than the
+	largest one.
 - let wbits be the number of bits to represent the total number of words in the file.
 - for each node:
 	- 1 bit: is node final?
@@ -35,6 +54,31 @@ import (
 			if this is not the first edge:
 				nskip: count
 			abits: location in bits of the node to jump to from start of file.
+- if the file contains a value table, starting at the next byte boundary:
+	- for each word, in index order:
+		vbits: value
+- 4 bytes: CRC-32C (Castagnoli) checksum of all of the preceding bytes
+
+In the aligned layout, cbits, abits and each nskip are multiples of 8, and
+there are no fallthrough nodes. Each node has a header of fixed width in
+place of the fields after the fallthrough bit:
+	- 1 bit: the node has no edges
+	- 1 bit: zero
+	- 4 bits: nskip in bytes
+	- cbits: number of edges minus one, or zero if there are none
+Then the edges follow as above, so that every field of a node is made of
+whole bytes and can be read without decoding bits. The characters of a
+node's edges are different, so the number of edges minus one always fits in
+cbits. The file is larger, but faster to search when it is opened with Load.
+
+Version 1 files have a shorter header, no value table and no checksum. Nodes
+are stored in the same way.
+- 4 bytes - total size of file
+- 1 byte: cbits
+- 1 byte: abits
+- 7code - number of words
+- 7code - number of nodes
+- 7code - number of edges
 
 We define 7code to be an unsigned that can be read the following way:
 
@@ -60,16 +104,184 @@ func (d *dawg) Save(filename string) (int64, error) {
 	return d.Write(f)
 }
 
-func readUint32(r io.ReaderAt, at int64) uint32 {
+// formatVersion is the version of the file format written by Write()
+const formatVersion = 2
+
+// header flags
+const (
+	flagValues = 1 << iota
+	flagAligned
+	flagBytes
+	flagAlphabet
+	flagsKnown = flagValues | flagAligned | flagBytes | flagAlphabet
+)
+
+// headerBits is the length of the fixed part of the header
+const headerBits = (4 + 4 + 1 + 8 + 1 + 1 + 1) * 8
+
+// magic identifies the file, following the zero where version 1 files
+// stored their size.
+const magic = "DAWG"
+
+// checksumBytes is the length of the checksum at the end of the file
+const checksumBytes = 4
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// header contains the information at the start of the file
+type header struct {
+	version         int
+	size            int64
+	hasValues       bool
+	aligned         bool
+	byteKeys        bool
+	alphabet        []rune // characters used, if the file has an alphabet
+	cbits           int64
+	abits           int64
+	vbits           int64
+	numAdded        int
+	numNodes        int
+	numEdges        int
+	firstNodeOffset int64 // first node offset in bits in the file
+}
+
+// readHeader reads the header of the dawg at the start of f. It returns
+// ErrTruncated if the data is shorter than the header says, ErrVersion if it
+// is in a format that is not supported, or ErrCorrupt if the header is not
+// valid.
+func readHeader(f io.ReaderAt) (header, error) {
+	var h header
+	size, err := readUint32(f, 0)
+	if err != nil {
+		return h, err
+	}
+
+	r := newBitSeeker(f)
+	h.version = 1
+	h.size = int64(size)
+	hasAlphabet := false
+	minSize := int64(9)
+	r.Seek(32, 0)
+
+	if size == 0 {
+		var data [14]byte
+		if _, err := f.ReadAt(data[:], 4); err != nil {
+			return h, readError(err)
+		}
+
+		if string(data[:4]) != magic {
+			return h, ErrCorrupt
+		}
+
+		h.version = int(data[4])
+		h.size = int64(binary.BigEndian.Uint64(data[5:13]))
+		flags := data[13]
+		if h.version != formatVersion || flags&^flagsKnown != 0 {
+			return h, ErrVersion
+		}
+
+		h.hasValues = flags&flagValues != 0
+		h.aligned = flags&flagAligned != 0
+		h.byteKeys = flags&flagBytes != 0
+		hasAlphabet = flags&flagAlphabet != 0
+		minSize = headerBits/8 + 3 + checksumBytes
+		r.Seek(headerBits-16, 0)
+	}
+
+	// make sure all of the data is there.
+	if h.size < minSize {
+		return h, ErrCorrupt
+	}
+	if _, err := f.ReadAt(make([]byte, 1), h.size-1); err != nil {
+		return h, readError(err)
+	}
+
+	h.cbits = int64(r.ReadBits(8))
+	h.abits = int64(r.ReadBits(8))
+	if h.hasValues {
+		h.vbits = int64(r.ReadBits(8))
+	}
+	h.numAdded = int(readUnsigned(&r))
+	h.numNodes = int(readUnsigned(&r))
+	h.numEdges = int(readUnsigned(&r))
+	if hasAlphabet {
+		var ok bool
+		h.alphabet, ok = readAlphabet(&r, h.size)
+		if !ok || h.byteKeys && h.alphabet[len(h.alphabet)-1] > 0xff {
+			return h, ErrCorrupt
+		}
+	}
+	h.firstNodeOffset = r.Tell()
+
+	if h.cbits > 31 || h.byteKeys && h.cbits > 8 || h.abits == 0 || h.abits > 64 || h.vbits > 64 ||
+		h.numAdded < 0 || h.valuesOffset()*8 < h.firstNodeOffset {
+		return h, ErrCorrupt
+	} else if h.aligned && (h.cbits%8 != 0 || h.abits%8 != 0) {
+		return h, ErrCorrupt
+	}
+
+	return h, nil
+}
+
+// hasChecksum returns true if the file ends with a checksum
+func (h *header) hasChecksum() bool {
+	return h.version > 1
+}
+
+// valuesOffset returns the offset in bytes of the value table
+func (h *header) valuesOffset() int64 {
+	end := h.size
+	if h.hasChecksum() {
+		end -= checksumBytes
+	}
+	return end - (int64(h.numAdded)*h.vbits+7)/8
+}
+
+// Verify checks that the dawg at the start of r is complete, and that its
+// checksum matches. It returns ErrTruncated, ErrVersion or ErrCorrupt if it
+// is not. Version 1 files have no checksum, so only their length is checked.
+func Verify(r io.ReaderAt) error {
+	h, err := readHeader(r)
+	if err != nil || !h.hasChecksum() {
+		return err
+	}
+
+	crc := crc32.New(castagnoli)
+	if _, err := io.Copy(crc, io.NewSectionReader(r, 0, h.size-checksumBytes)); err != nil {
+		return readError(err)
+	}
+
+	expected, err := readUint32(r, h.size-checksumBytes)
+	if err != nil {
+		return err
+	}
+
+	if crc.Sum32() != expected {
+		return ErrCorrupt
+	}
+
+	return nil
+}
+
+func readUint32(r io.ReaderAt, at int64) (uint32, error) {
 	data := make([]byte, 4, 4)
 	_, err := r.ReadAt(data, at)
 	if err != nil {
-		log.Panic(err)
+		return 0, readError(err)
 	}
 	return (uint32(data[0]) << 24) |
 		(uint32(data[1]) << 16) |
 		(uint32(data[2]) << 8) |
-		(uint32(data[3]) << 0)
+		(uint32(data[3]) << 0), nil
+}
+
+// readError converts an error from reading past the end of the file into
+// ErrTruncated.
+func readError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrTruncated
+	}
+	return err
 }
 
 func (n *node) isFallthrough(id int) bool {
@@ -79,6 +291,10 @@ func (n *node) isFallthrough(id int) bool {
 // Save writes the dawg to an io.Writer. Returns the number of bytes written
 func (d *dawg) Write(wIn io.Writer) (int64, error) {
 	if d.r != nil {
+		if err := d.acquire(); err != nil {
+			return 0, err
+		}
+		defer d.release()
 		return io.Copy(wIn, io.NewSectionReader(d.r, 0, d.size))
 	}
 
@@ -86,33 +302,198 @@ func (d *dawg) Write(wIn io.Writer) (int64, error) {
 		return 0, errors.New("dawg not finished")
 	}
 
-	w := newBitWriter(wIn)
+	return d.encode(wIn, false)
+}
+
+// WriteOptions controls how a dawg is written by WriteWithOptions
+type WriteOptions struct {
+	// Use a layout where the fields of each edge are whole bytes. The file
+	// is larger, but lookups are faster.
+	Aligned bool
+}
+
+// WriteWithOptions writes the dawg to an io.Writer using the given layout.
+// Returns the number of bytes written. Read and Load detect the layout
+// automatically.
+func (d *dawg) WriteWithOptions(w io.Writer, opts WriteOptions) (int64, error) {
+	if d.r == nil {
+		if !d.finished {
+			return 0, errors.New("dawg not finished")
+		}
+		return d.encode(w, opts.Aligned)
+	}
+
+	if d.aligned == opts.Aligned {
+		return d.Write(w)
+	}
+
+	if err := d.acquire(); err != nil {
+		return 0, err
+	}
+	defer d.release()
+
+	decoded, err := d.decode()
+	if err != nil {
+		return 0, err
+	}
+	return decoded.encode(w, opts.Aligned)
+}
+
+// decode reads the nodes and values of a dawg from its file, so that they can
+// be encoded again.
+func (d *dawg) decode() (*dawg, error) {
+	r := newBitSeeker(d.r)
+	defer d.addCacheStats(&r)
+
+	// number the nodes in the order they are stored, which keeps each
+	// fallthrough node next to the node before it.
+	ids := make(map[int64]int)
+	addresses := make([]int64, d.numNodes)
+	pos := d.firstNodeOffset
+	for i := range addresses {
+		ids[pos] = i
+		addresses[i] = pos
+
+		var err error
+		if pos, err = d.checkNode(&r, pos, d.valuesOffset*8); err != nil {
+			return nil, err
+		}
+	}
+
+	nodes := make([]node, d.numNodes)
+	decoded := make([]nodeResult, d.numNodes)
+	for i, address := range addresses {
+		decoded[i] = d.getNode(&r, address)
+		nodes[i] = node{final: decoded[i].final, count: -1}
+		for _, edge := range decoded[i].edges {
+			nodes[i].edges = append(nodes[i].edges, edgeStart{node: ids[edge.node], ch: edge.ch})
+		}
+	}
+
+	// the number of words beneath each child is the difference between the
+	// skip counts of its edge and the next one.
+	nodes[0].count = d.numAdded
+	queue := []int{0}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		edges := decoded[id].edges
+		for i, edge := range edges {
+			next := nodes[id].count
+			if i+1 < len(edges) {
+				next = edges[i+1].count
+			}
+
+			child := &nodes[ids[edge.node]]
+			if child.count < 0 {
+				child.count = next - edge.count
+				queue = append(queue, ids[edge.node])
+			}
+		}
+	}
+
+	var values []uint64
+	if d.hasValues {
+		values = make([]uint64, d.numAdded)
+		r.Seek(d.valuesOffset*8, 0)
+		for i := range values {
+			values[i] = r.ReadBits(d.vbits)
+		}
+	}
+
+	return &dawg{
+		finished: true,
+		nodes:    nodes,
+		values:   values,
+		numAdded: d.numAdded,
+		numNodes: d.numNodes,
+		numEdges: d.numEdges,
+		byteKeys: d.byteKeys,
+	}, nil
+}
+
+// encode writes the nodes of a dawg that is being built
+func (d *dawg) encode(wIn io.Writer, aligned bool) (int64, error) {
+	crc := crc32.New(castagnoli)
+	w := newBitWriter(io.MultiWriter(wIn, crc))
 
 	// get maximum character and calculate cbits
 	// record node addresses, calculate counts and number of edges
 	addresses := make([]uint64, d.NumNodes(), d.NumNodes())
+	alphabet := alphabetOf(d.nodes)
 	var maxChar rune
+	if len(alphabet) > 0 {
+		maxChar = alphabet[len(alphabet)-1]
+	}
+
+	// roundBits rounds a number of bits up to whole bytes in the aligned
+	// layout
+	roundBits := func(n uint64) uint64 {
+		if aligned {
+			return (n + 7) &^ 7
+		}
+		return n
+	}
+
+	// nodes are never written as fallthrough nodes in the aligned layout.
+	isFallthrough := func(i int) bool {
+		return !aligned && d.nodes[i].isFallthrough(i)
+	}
+
+	cbits := roundBits(uint64(bits.Len32(uint32(maxChar))))
+
+	// store the characters as their positions in an alphabet, if the
+	// smaller edges make up for the size of the alphabet.
+	var graphEdges uint64
 	for _, node := range d.nodes {
-		for _, edge := range node.edges {
-			if edge.ch > maxChar {
-				maxChar = edge.ch
-			}
+		graphEdges += uint64(len(node.edges))
+	}
+
+	codes := make(map[rune]uint64)
+	if saved := cbits - roundBits(codeBits(alphabet)); saved*graphEdges > alphabetBits(alphabet) {
+		cbits = roundBits(codeBits(alphabet))
+		for i, ch := range alphabet {
+			codes[ch] = uint64(i)
 		}
+	} else {
+		alphabet = nil
+	}
+
+	// code returns the number stored for a character
+	code := func(ch rune) uint64 {
+		if alphabet == nil {
+			return uint64(ch)
+		}
+		return codes[ch]
 	}
 
-	cbits := uint64(bits.Len(uint(maxChar)))
-	wbits := uint64(bits.Len(uint(d.NumAdded())))
-	nskiplen := uint64(bits.Len(uint(wbits)))
+	wbits := uint64(countBits(d.NumAdded(), aligned))
+	nskiplen := uint64(bits.Len64(wbits))
+
+	// calculate vbits from the largest value
+	hasValues := d.values != nil
+	var vbits uint64
+	for _, value := range d.values {
+		if l := uint64(bits.Len64(value)); l > vbits {
+			vbits = l
+		}
+	}
 
 	// let abits = 1
-	abits := uint64(1)
+	abits := roundBits(1)
 	var pos uint64
 	for {
-		// position = 32 + 8 + 8 + encoded length of number of words, nodes, and edges
-		pos = 32 + 8 + 8
+		// position = header + encoded length of number of words, nodes, and edges
+		pos = headerBits
+		if hasValues {
+			pos += 8
+		}
 		pos += unsignedLength(uint64(d.NumAdded())) * 8
 		pos += unsignedLength(uint64(d.NumNodes())) * 8
 		pos += unsignedLength(uint64(d.NumEdges())) * 8
+		if alphabet != nil {
+			pos += alphabetBits(alphabet)
+		}
 
 		// for each node,
 		for i := range addresses {
@@ -127,7 +508,7 @@ func (d *dawg) Write(wIn io.Writer) (int64, error) {
 			// fallthrough?
 			pos++
 
-			if node.isFallthrough((i)) {
+			if isFallthrough(i) {
 				pos += cbits
 			} else {
 				// add number of edges
@@ -146,9 +527,12 @@ func (d *dawg) Write(wIn io.Writer) (int64, error) {
 					skip += d.nodes[edge.node].count
 				}
 
-				nskipbits := uint64(bits.Len(uint(skip)))
+				nskipbits := roundBits(uint64(bits.Len(uint(skip))))
 
-				if numEdges != 1 {
+				if aligned {
+					// the rest of the header byte, and the number of edges
+					pos += 5 + cbits
+				} else if numEdges != 1 {
 					pos += unsignedLength(numEdges) * 8
 					pos += nskiplen
 				}
@@ -161,23 +545,52 @@ func (d *dawg) Write(wIn io.Writer) (int64, error) {
 		}
 
 		// if file position fits into abits, then break out.
-		if uint64(bits.Len(uint(pos))) <= abits {
+		if uint64(bits.Len64(pos)) <= abits {
 			break
 		}
-		abits = uint64(bits.Len(uint(pos)))
+		abits = roundBits(uint64(bits.Len64(pos)))
+	}
+
+	size := (pos+7)/8 + checksumBytes
+	if hasValues {
+		size += (uint64(d.NumAdded())*vbits + 7) / 8
 	}
 
-	size := (pos + 7) / 8
+	var flags uint64
+	if hasValues {
+		flags |= flagValues
+	}
+	if aligned {
+		flags |= flagAligned
+	}
+	if d.byteKeys {
+		flags |= flagBytes
+	}
+	if alphabet != nil {
+		flags |= flagAlphabet
+	}
 
-	// write file size, cbits, abits
-	w.WriteBits(size, 32)
+	// write magic, version, file size, flags, cbits, abits
+	w.WriteBits(0, 32)
+	for i := 0; i < len(magic); i++ {
+		w.WriteBits(uint64(magic[i]), 8)
+	}
+	w.WriteBits(formatVersion, 8)
+	w.WriteBits(size, 64)
+	w.WriteBits(flags, 8)
 	w.WriteBits(cbits, 8)
 	w.WriteBits(abits, 8)
+	if hasValues {
+		w.WriteBits(vbits, 8)
+	}
 
 	// write number of words, nodes, and edges.
 	writeUnsigned(w, uint64(d.NumAdded()))
 	writeUnsigned(w, uint64(d.NumNodes()))
 	writeUnsigned(w, uint64(d.NumEdges()))
+	if alphabet != nil {
+		writeAlphabet(w, alphabet)
+	}
 
 	// for each edge,
 	for i := range addresses {
@@ -190,9 +603,9 @@ func (d *dawg) Write(wIn io.Writer) (int64, error) {
 			w.WriteBits(0, 1)
 		}
 
-		if node.isFallthrough(i) {
+		if isFallthrough(i) {
 			w.WriteBits(1, 1)
-			w.WriteBits(uint64(node.edges[0].ch), int(cbits))
+			w.WriteBits(code(node.edges[0].ch), int(cbits))
 		} else {
 			w.WriteBits(0, 1)
 			skip := 0
@@ -204,9 +617,11 @@ func (d *dawg) Write(wIn io.Writer) (int64, error) {
 				skip += d.nodes[edge.node].count
 			}
 
-			nskipbits := uint64(bits.Len(uint(skip)))
+			nskipbits := roundBits(uint64(bits.Len64(uint64(skip))))
 
-			if len(node.edges) == 1 {
+			if aligned {
+				writeAlignedHeader(w, len(node.edges), nskipbits, cbits)
+			} else if len(node.edges) == 1 {
 				w.WriteBits(1, 1)
 			} else {
 				w.WriteBits(0, 1)
@@ -216,7 +631,7 @@ func (d *dawg) Write(wIn io.Writer) (int64, error) {
 
 			for index, edge := range node.edges {
 				// write character, address
-				w.WriteBits(uint64(edge.ch), int(cbits))
+				w.WriteBits(code(edge.ch), int(cbits))
 				if index > 0 {
 					w.WriteBits(uint64(count), int(nskipbits))
 				}
@@ -228,70 +643,171 @@ func (d *dawg) Write(wIn io.Writer) (int64, error) {
 
 	w.Flush()
 
+	// write the value table
+	if hasValues {
+		for i := 0; i < d.NumAdded(); i++ {
+			var value uint64
+			if i < len(d.values) {
+				value = d.values[i]
+			}
+			w.WriteBits(value, int(vbits))
+		}
+		w.Flush()
+	}
+
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	// write the checksum of everything so far
+	if err := newBitWriter(wIn).WriteBits(uint64(crc.Sum32()), 32); err != nil {
+		return 0, err
+	}
+
 	return int64(size), nil
 }
 
+// mappedReader is a file that has been mapped into memory
+type mappedReader interface {
+	io.ReaderAt
+	io.Closer
+}
+
 // Load loads the dawg from a file
 func Load(filename string) (Finder, error) {
-	f, err := mmap.Open(filename)
+	f, err := openMapped(filename)
 	if err != nil {
 		return nil, err
 	}
 
-	return Read(f, 0)
+	finder, err := Read(f, 0)
+	if err != nil {
+		f.Close()
+	}
+	return finder, err
 }
 
-const edgesOffset = (32*4 + 8 + 8)
-
 // Read returns a finder that accesses the dawg in-place using the
-// given io.ReaderAt
+// given io.ReaderAt. It returns ErrTruncated if the data is shorter than
+// the header says, ErrVersion if it is in a newer format, or ErrCorrupt if
+// the header is not valid.
 func Read(f io.ReaderAt, offset int64) (Finder, error) {
-	size := readUint32(f, offset)
 	if offset != 0 {
-		f = io.NewSectionReader(f, offset, int64(size))
+		f = io.NewSectionReader(f, offset, math.MaxInt64-offset)
 	}
 
-	r := newBitSeeker(f)
+	h, err := readHeader(f)
+	if err != nil {
+		return nil, err
+	}
 
-	r.Seek(32, 0)
-	cbits := r.ReadBits(8)
-	abits := r.ReadBits(8)
-	numAdded := int(readUnsigned(&r))
-	numNodes := int(readUnsigned(&r))
-	numEdges := int(readUnsigned(&r))
-	firstNodeOffset := r.Tell()
+	if offset != 0 {
+		f = io.NewSectionReader(f, 0, h.size)
+	}
+
+	return newReader(f, h), nil
+}
+
+// newReader returns a dawg that accesses f in-place, using the header that
+// was read from it
+func newReader(f io.ReaderAt, h header) *dawg {
+	r := newBitSeeker(f)
+	r.Seek(h.firstNodeOffset, 0)
 	hasEmpty := r.ReadBits(1) == 1
-	wbits := int64(bits.Len(uint(numAdded)))
-	dawg := &dawg{
+	d := &dawg{
 		finished:        true,
-		numAdded:        numAdded,
-		numNodes:        numNodes,
-		numEdges:        numEdges,
-		abits:           int64(abits),
-		cbits:           int64(cbits),
-		wbits:           wbits,
+		numAdded:        h.numAdded,
+		numNodes:        h.numNodes,
+		numEdges:        h.numEdges,
+		abits:           h.abits,
+		cbits:           h.cbits,
+		wbits:           countBits(h.numAdded, h.aligned),
+		aligned:         h.aligned,
 		hasEmptyWord:    hasEmpty,
-		firstNodeOffset: firstNodeOffset,
+		firstNodeOffset: h.firstNodeOffset,
+		hasValues:       h.hasValues,
+		byteKeys:        h.byteKeys,
+		alphabet:        h.alphabet,
+		vbits:           h.vbits,
+		valuesOffset:    h.valuesOffset(),
 		r:               f,
-		size:            int64(size),
+		size:            h.size,
+		idle:            make(chan struct{}),
+	}
+	if m, ok := f.(inPlace); ok && h.aligned && int64(len(m.Bytes())) >= h.size {
+		d.data = m.Bytes()[:h.size]
+	}
+	return d
+}
+
+// closedBit is set in users once the dawg is closed
+const closedBit = 1 << 62
+
+// acquire marks the start of a query, so that Close will wait for it. It
+// returns ErrClosed if the dawg has been closed.
+func (d *dawg) acquire() error {
+	if atomic.AddInt64(&d.users, 1)&closedBit != 0 {
+		d.release()
+		return ErrClosed
 	}
+	return nil
+}
+
+// begin is like acquire, but returns false if the dawg has been closed. It is
+// used by queries which cannot return an error, which then find nothing.
+func (d *dawg) begin() bool {
+	return d.acquire() == nil
+}
 
-	return dawg, nil
+// release marks the end of a query
+func (d *dawg) release() {
+	if atomic.AddInt64(&d.users, -1) == closedBit {
+		d.idleOnce.Do(func() { close(d.idle) })
+	}
 }
 
-// Close ...
+// Close closes the file of a dawg that was opened with Load(). It waits for
+// queries in progress on other goroutines to finish. After this, queries
+// return ErrClosed, or find nothing if they cannot return an error. Calling
+// Close again returns ErrClosed.
+//
+// Close must not be called from inside the callback of a query on the same
+// dawg, such as Enumerate or Match, or from the body of a loop over
+// Prefixes. That query is still in progress, so Close would wait for it
+// forever. Stop the query first, and then call Close.
 func (d *dawg) Close() error {
+	for {
+		users := atomic.LoadInt64(&d.users)
+		if users&closedBit != 0 {
+			return ErrClosed
+		}
+
+		if atomic.CompareAndSwapInt64(&d.users, users, users|closedBit) {
+			if users == 0 {
+				d.idleOnce.Do(func() { close(d.idle) })
+			}
+			break
+		}
+	}
+
+	<-d.idle
 	if closer, ok := d.r.(io.Closer); ok {
 		return closer.Close()
 	}
 	return nil
 }
 
-func (d *dawg) getEdge(r *bitSeeker, eStart edgeStart) (edgeEnd, bool, bool) {
+func (d *dawg) getEdge(r *bitSeeker, node int64, ch rune) (edgeEnd, bool, bool) {
+	if cached := d.cachedNode(r, node); cached != nil {
+		return cached.getEdge(ch)
+	} else if d.aligned {
+		return d.getAlignedEdge(r, node, ch)
+	}
+
 	var edgeEnd edgeEnd
 	var final, ok bool
 	if d.numEdges > 0 {
-		pos := int64(eStart.node)
+		pos := node
 		if pos == 0 {
 			// its the first node
 			pos = d.firstNodeOffset
@@ -302,10 +818,9 @@ func (d *dawg) getEdge(r *bitSeeker, eStart edgeStart) (edgeEnd, bool, bool) {
 		fallthr := int(r.ReadBits(1))
 
 		if fallthr == 1 {
-			ch := rune(r.ReadBits(d.cbits))
-			if ch == eStart.ch {
+			if d.readLetter(r) == ch {
 				edgeEnd.count = nodeFinal
-				edgeEnd.node = int(r.Tell())
+				edgeEnd.node = r.Tell()
 				final = r.ReadBits(1) == 1
 				ok = true
 			}
@@ -327,19 +842,19 @@ func (d *dawg) getEdge(r *bitSeeker, eStart edgeStart) (edgeEnd, bool, bool) {
 				}
 
 				r.Seek(seekTo, 0)
-				ch := rune(r.ReadBits(d.cbits))
-				if ch == eStart.ch {
+				edgeCh := d.readLetter(r)
+				if edgeCh == ch {
 					if i > 0 {
 						edgeEnd.count = int(r.ReadBits(nskip))
 					} else {
 						edgeEnd.count = nodeFinal
 					}
-					edgeEnd.node = int(r.ReadBits(d.abits))
-					r.Seek(int64(edgeEnd.node), 0)
+					edgeEnd.node = int64(r.ReadBits(d.abits))
+					r.Seek(edgeEnd.node, 0)
 					final = r.ReadBits(1) == 1
 					ok = true
 				}
-				return int(ch - eStart.ch)
+				return int(edgeCh - ch)
 			})
 		}
 	}
@@ -347,8 +862,118 @@ func (d *dawg) getEdge(r *bitSeeker, eStart edgeStart) (edgeEnd, bool, bool) {
 	return edgeEnd, final, ok
 }
 
+// edgeSpan describes where the words beginning with an edge lie among the
+// words beneath a node.
+type edgeSpan struct {
+	before  int // number of words beneath the node that sort before the edge
+	through int // number of words beneath the node up to the end of the edge
+	node    int64
+	final   bool
+	ok      bool
+}
+
+// getEdgeSpan finds the edge for the character, like getEdge. The span is
+// calculated from the skip counts of the edge and the one after it. Total is
+// the number of words beneath the node, including the node itself, and is
+// used when there is no following edge. If there is no edge for the
+// character, before and through are both the number of words that sort
+// before it.
+func (d *dawg) getEdgeSpan(r *bitSeeker, node int64, total int, ch rune) edgeSpan {
+	if cached := d.cachedNode(r, node); cached != nil {
+		return cached.getEdgeSpan(total, ch)
+	} else if d.aligned {
+		return d.getAlignedEdgeSpan(r, node, total, ch)
+	}
+
+	var span edgeSpan
+	pos := node
+	if pos == 0 {
+		// its the first node
+		pos = d.firstNodeOffset
+	}
+
+	r.Seek(pos, 0)
+	nodeFinal := int(r.ReadBits(1))
+	fallthr := r.ReadBits(1)
+
+	if fallthr == 1 {
+		edgeCh := d.readLetter(r)
+		if ch < edgeCh {
+			span.before = nodeFinal
+			span.through = nodeFinal
+		} else if ch > edgeCh {
+			span.before = total
+			span.through = total
+		} else {
+			span.before = nodeFinal
+			span.through = total
+			span.node = r.Tell()
+			span.final = r.ReadBits(1) == 1
+			span.ok = true
+		}
+		return span
+	}
+
+	singleEdge := r.ReadBits(1)
+	numEdges := 1
+	nskiplen := int64(bits.Len(uint(d.wbits)))
+	nskip := int64(0)
+	if singleEdge != 1 {
+		numEdges = int(readUnsigned(r))
+		nskip = int64(r.ReadBits(nskiplen))
+	}
+
+	pos = r.Tell()
+	seekEdge := func(i int) {
+		seekTo := pos + int64(i)*int64(d.cbits+nskip+d.abits)
+		if i > 0 {
+			seekTo -= nskip
+		}
+		r.Seek(seekTo, 0)
+	}
+
+	// countAt returns the number of words before edge i
+	countAt := func(i int) int {
+		if i == 0 {
+			return nodeFinal
+		} else if i == numEdges {
+			return total
+		}
+		seekEdge(i)
+		r.Skip(d.cbits)
+		return int(r.ReadBits(nskip))
+	}
+
+	i := bsearch(numEdges, func(i int) int {
+		seekEdge(i)
+		return int(d.readLetter(r) - ch)
+	})
+
+	if i < numEdges {
+		seekEdge(i)
+		span.ok = d.readLetter(r) == ch
+	}
+
+	span.before = countAt(i)
+	if !span.ok {
+		span.through = span.before
+		return span
+	}
+
+	seekEdge(i)
+	r.Skip(d.cbits)
+	if i > 0 {
+		r.Skip(nskip)
+	}
+	span.node = int64(r.ReadBits(d.abits))
+	span.through = countAt(i + 1)
+	r.Seek(span.node, 0)
+	span.final = r.ReadBits(1) == 1
+	return span
+}
+
 type nodeResult struct {
-	node  int
+	node  int64
 	final bool
 	edges []edgeResult
 }
@@ -356,12 +981,20 @@ type nodeResult struct {
 type edgeResult struct {
 	ch    rune
 	count int
-	node  int
+	node  int64
 }
 
-func (d *dawg) getNode(r *bitSeeker, node int) nodeResult {
+func (d *dawg) getNode(r *bitSeeker, node int64) nodeResult {
+	if cached := d.cachedNode(r, node); cached != nil {
+		result := cached.nodeResult
+		result.node = node
+		return result
+	} else if d.aligned {
+		return d.getAlignedNode(r, node)
+	}
+
 	var result nodeResult
-	pos := int64(node)
+	pos := node
 	if pos == 0 {
 		// its the first node
 		pos = d.firstNodeOffset
@@ -376,9 +1009,9 @@ func (d *dawg) getNode(r *bitSeeker, node int) nodeResult {
 
 	if fallthr == 1 {
 		result.edges = append(result.edges, edgeResult{
-			ch:    rune(r.ReadBits(d.cbits)),
+			ch:    d.readLetter(r),
 			count: int(nodeFinal),
-			node:  int(r.Tell()),
+			node:  r.Tell(),
 		})
 	} else {
 		nskiplen := int64(bits.Len(uint(d.wbits)))
@@ -392,7 +1025,7 @@ func (d *dawg) getNode(r *bitSeeker, node int) nodeResult {
 		}
 
 		for i := uint64(0); i < numEdges; i++ {
-			ch := r.ReadBits(int64(d.cbits))
+			ch := d.readLetter(r)
 			var count uint64
 			if i > 0 {
 				count = r.ReadBits(int64(nskip))
@@ -401,39 +1034,154 @@ func (d *dawg) getNode(r *bitSeeker, node int) nodeResult {
 			}
 			address := r.ReadBits(int64(d.abits))
 			result.edges = append(result.edges, edgeResult{
-				ch:    rune(ch),
+				ch:    ch,
 				count: int(count),
-				node:  int(address),
+				node:  int64(address),
 			})
 		}
 	}
 	return result
 }
 
-// DumpFile prints out the file
-func DumpFile(f io.ReaderAt) {
-	r := newBitSeeker(f)
-	size := r.ReadBits(32)
-	fmt.Printf("[%08x] Size=%v bytes\n", r.Tell()-32, size)
+// countBits returns the number of bits needed for the largest count in a
+// file with the given number of words. In the aligned layout, it is rounded
+// up to whole bytes.
+func countBits(numAdded int, aligned bool) int64 {
+	n := int64(bits.Len64(uint64(numAdded)))
+	if aligned {
+		n = (n + 7) &^ 7
+	}
+	return n
+}
+
+// edgeList locates the edges of a node in the file, so that any one of them
+// can be read without decoding the others.
+type edgeList struct {
+	start   int64 // position in bits of the first edge
+	count   int   // number of edges
+	nskip   int64 // bits in the skip count of each edge after the first
+	final   int   // 1 if the node is final, which is the skip of the first edge
+	fallthr bool  // the single edge has no address, and leads to the next node
+}
+
+// readEdgeList reads the header of the node to find its edges
+func (d *dawg) readEdgeList(r *bitSeeker, node int64) edgeList {
+	pos := node
+	if pos == 0 {
+		// its the first node
+		pos = d.firstNodeOffset
+	}
+
+	r.Seek(pos, 0)
+	list := edgeList{final: int(r.ReadBits(1)), count: 1}
+	if d.aligned {
+		r.Skip(1)
+		empty := r.ReadBits(1) == 1
+		r.Skip(1)
+		list.nskip = int64(r.ReadBits(4)) * 8
+		list.count = int(r.ReadBits(d.cbits)) + 1
+		if empty {
+			list.count = 0
+		}
+		list.start = r.Tell()
+		return list
+	}
+
+	if r.ReadBits(1) == 1 {
+		list.fallthr = true
+		list.start = r.Tell()
+		return list
+	}
+
+	if r.ReadBits(1) != 1 {
+		list.count = int(readUnsigned(r))
+		list.nskip = int64(r.ReadBits(int64(bits.Len(uint(d.wbits)))))
+	}
+	list.start = r.Tell()
+	return list
+}
+
+// seekEdge moves to the start of edge i
+func (d *dawg) seekEdge(r *bitSeeker, list edgeList, i int) {
+	pos := list.start + int64(i)*(d.cbits+list.nskip+d.abits)
+	if i > 0 {
+		pos -= list.nskip
+	}
+	r.Seek(pos, 0)
+}
+
+// edgeChar reads the character of edge i
+func (d *dawg) edgeChar(r *bitSeeker, list edgeList, i int) rune {
+	d.seekEdge(r, list, i)
+	return d.readLetter(r)
+}
+
+// edgeAt reads edge i
+func (d *dawg) edgeAt(r *bitSeeker, list edgeList, i int) edgeResult {
+	d.seekEdge(r, list, i)
+	edge := edgeResult{ch: d.readLetter(r), count: list.final}
+	if list.fallthr {
+		edge.node = r.Tell()
+		return edge
+	}
+
+	if i > 0 {
+		edge.count = int(r.ReadBits(list.nskip))
+	}
+	edge.node = int64(r.ReadBits(d.abits))
+	return edge
+}
 
-	cbits := r.ReadBits(8)
-	fmt.Printf("[%08x] cbits=%d\n", r.Tell()-8, cbits)
+// searchEdges returns the index of the first edge whose character is not
+// less than ch, or the number of edges if there is none.
+func (d *dawg) searchEdges(r *bitSeeker, list edgeList, ch rune) int {
+	return sort.Search(list.count, func(i int) bool {
+		return d.edgeChar(r, list, i) >= ch
+	})
+}
 
-	abits := r.ReadBits(8)
-	fmt.Printf("[%08x] abits=%d\n", r.Tell()-8, cbits)
+// writeAlignedHeader writes the part of a node's header in the aligned layout
+// that follows the fallthrough bit.
+func writeAlignedHeader(w *bitWriter, numEdges int, nskipbits, cbits uint64) {
+	if numEdges == 0 {
+		w.WriteBits(1, 1)
+	} else {
+		w.WriteBits(0, 1)
+	}
+	w.WriteBits(0, 1)
+	w.WriteBits(nskipbits/8, 4)
+	if numEdges == 0 {
+		w.WriteBits(0, int(cbits))
+	} else {
+		w.WriteBits(uint64(numEdges-1), int(cbits))
+	}
+}
 
-	wordCount := readUnsigned(&r)
-	fmt.Printf("[%08x] WordCount=%v\n", r.Tell()-int64(unsignedLength(wordCount)*8), wordCount)
+// DumpFile prints out the file
+func DumpFile(f io.ReaderAt) {
+	h, err := readHeader(f)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
 
-	nodeCount := readUnsigned(&r)
-	fmt.Printf("[%08x] NodeCount=%v\n", r.Tell()-int64(unsignedLength(nodeCount)*8), nodeCount)
-	wbits := bits.Len(uint(wordCount))
+	fmt.Printf("Version=%d Size=%v bytes\n", h.version, h.size)
+	fmt.Printf("cbits=%d abits=%d values=%v vbits=%d aligned=%v bytes=%v\n", h.cbits, h.abits, h.hasValues, h.vbits, h.aligned, h.byteKeys)
 
-	edgeCount := readUnsigned(&r)
-	fmt.Printf("[%08x] EdgeCount=%v\n", r.Tell()-int64(unsignedLength(edgeCount)*8), edgeCount)
+	cbits, abits, vbits := h.cbits, h.abits, h.vbits
+	wordCount := uint64(h.numAdded)
+	nodeCount := uint64(h.numNodes)
+	fmt.Printf("WordCount=%v NodeCount=%v EdgeCount=%v\n", wordCount, nodeCount, h.numEdges)
+	if h.alphabet != nil {
+		fmt.Printf("Alphabet=%q\n", string(h.alphabet))
+	}
 
+	wbits := countBits(h.numAdded, h.aligned)
 	nskiplen := bits.Len(uint(wbits))
 
+	r := newBitSeeker(f)
+	r.Seek(h.firstNodeOffset, 0)
+
 	for i := 0; i < int(nodeCount); i++ {
 		at := r.Tell()
 		final := r.ReadBits(1)
@@ -441,14 +1189,21 @@ func DumpFile(f io.ReaderAt) {
 
 		if fallthr == 1 {
 			ch := r.ReadBits(int64(cbits))
-			fmt.Printf("[%08x] Node final=%d ch='%c' (fallthrough)\n", at, final, rune(ch))
+			fmt.Printf("[%08x] Node final=%d ch='%c' (fallthrough)\n", at, final, letterOf(h.alphabet, ch))
 			continue
 		}
 
-		singleEdge := r.ReadBits(1)
 		edges := uint64(1)
 		nskip := uint64(0)
-		if singleEdge != 1 {
+		if h.aligned {
+			empty := r.ReadBits(1)
+			r.Skip(1)
+			nskip = r.ReadBits(4) * 8
+			edges = r.ReadBits(int64(cbits)) + 1
+			if empty == 1 {
+				edges = 0
+			}
+		} else if r.ReadBits(1) != 1 {
 			edges = readUnsigned(&r)
 			nskip = r.ReadBits(int64(nskiplen))
 		}
@@ -466,31 +1221,30 @@ func DumpFile(f io.ReaderAt) {
 			}
 			address := r.ReadBits(int64(abits))
 			fmt.Printf("[%08x] '%c' goto <%08x> skipping %d\n",
-				at, rune(ch), address, count)
+				at, letterOf(h.alphabet, ch), address, count)
+		}
+
+	}
+
+	if h.hasValues {
+		r.Seek(h.valuesOffset()*8, 0)
+		for i := uint64(0); i < wordCount; i++ {
+			at := r.Tell()
+			fmt.Printf("[%08x] Value %d=%d\n", at, i, r.ReadBits(vbits))
 		}
+	}
 
+	if h.hasChecksum() {
+		checksum, _ := readUint32(f, h.size-checksumBytes)
+		fmt.Printf("[%08x] Checksum=%08x\n", (h.size-checksumBytes)*8, checksum)
 	}
 }
 
 func writeUnsigned(w *bitWriter, n uint64) {
-	if n < 0x7f {
-		w.WriteBits(n, 8)
-	} else if n < 0x3fff {
-		w.WriteBits((n>>7)&0x7f|0x80, 8)
-		w.WriteBits(n&0x7f, 8)
-	} else if n < 0x1fffff {
-		w.WriteBits((n>>14)&0x7f|0x80, 8)
-		w.WriteBits((n>>7)&0x7f|0x80, 8)
-		w.WriteBits(n&0x7f, 8)
-	} else if n < 0xfffffff {
-		w.WriteBits((n>>21)&0x7f|0x80, 8)
-		w.WriteBits((n>>14)&0x7f|0x80, 8)
-		w.WriteBits((n>>7)&0x7f|0x80, 8)
-		w.WriteBits(n&0x7f, 8)
-	} else {
-		// could go further
-		log.Panic("Not implemented")
+	for i := unsignedLength(n) - 1; i > 0; i-- {
+		w.WriteBits((n>>(7*i))&0x7f|0x80, 8)
 	}
+	w.WriteBits(n&0x7f, 8)
 }
 
 func readUnsigned(r *bitSeeker) uint64 {
@@ -506,17 +1260,12 @@ func readUnsigned(r *bitSeeker) uint64 {
 }
 
 func unsignedLength(n uint64) uint64 {
-	if n < 0x7f {
-		return 1
-	} else if n < 0x3fff {
-		return 2
-	} else if n < 0x1fffff {
-		return 3
-	} else if n < 0xfffffff {
-		return 4
-	}
-	log.Panicf("Not implemented: %d", n)
-	return 0
+	length := uint64(1)
+	for n >= 0x80 {
+		n >>= 7
+		length++
+	}
+	return length
 }
 
 /** @param cmp returns target - i  or cmp(i, target)*/
@@ -0,0 +1,31 @@
+package dawg
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestUnsigned(t *testing.T) {
+	numbers := []uint64{0, 1, 0x7f, 0x80, 0x3fff, 0x4000, 0xfffffff, 0x10000000,
+		1 << 35, 1<<63 + 12345, 0xffffffffffffffff}
+
+	var buffer bytes.Buffer
+	w := newBitWriter(&buffer)
+	var length uint64
+	for _, n := range numbers {
+		writeUnsigned(w, n)
+		length += unsignedLength(n)
+	}
+	w.Flush()
+
+	if uint64(buffer.Len()) != length {
+		t.Errorf("Wrote %d bytes, but unsignedLength says %d", buffer.Len(), length)
+	}
+
+	r := newBitSeeker(bytes.NewReader(buffer.Bytes()))
+	for _, n := range numbers {
+		if got := readUnsigned(&r); got != n {
+			t.Errorf("Read %x, expected %x", got, n)
+		}
+	}
+}
@@ -24,5 +24,10 @@ which are also words, or looking up a word's index that you have previously adde
 After you have called Finish() on a Builder, you may choose to write it to disk using the
 Save() function. The DAWG can then be opened again later using the Load() function.
 When opened from disk, no memory is used. The structure is accessed in-place on disk.
+
+A Finder may be used by many goroutines at once. Each query keeps its own position
+in the file, and nothing is modified after Finish() or Load(). Close() waits for the
+queries in progress to finish. Any query made after it returns ErrClosed, or finds
+nothing if it cannot return an error.
 */
 package dawg
@@ -0,0 +1,44 @@
+package dawg
+
+import "errors"
+
+var (
+	// ErrOutOfOrder is returned when a word is not added in alphabetical
+	// order, or is a repeat of the previous word.
+	ErrOutOfOrder = errors.New("dawg: words not in alphabetical order")
+
+	// ErrMixedKeys is returned when a key of bytes is added to a dawg that
+	// already holds strings.
+	ErrMixedKeys = errors.New("dawg: tried to add bytes to a dawg of strings")
+
+	// ErrFinished is returned when a word is added after the dawg is finished.
+	ErrFinished = errors.New("dawg: tried to add to a finished dawg")
+
+	// ErrAborted is returned when a builder is finished after Abort was
+	// called.
+	ErrAborted = errors.New("dawg: builder was aborted")
+
+	// ErrStreamed is returned when a dawg that was finished using FinishTo
+	// is finished again. Its encoding was written out and not kept in
+	// memory.
+	ErrStreamed = errors.New("dawg: already written by FinishTo")
+
+	// ErrCorrupt is returned when a file does not contain a valid dawg.
+	ErrCorrupt = errors.New("dawg: corrupt file")
+
+	// ErrVersion is returned when a file uses a newer format than this
+	// package supports.
+	ErrVersion = errors.New("dawg: unsupported file version")
+
+	// ErrClosed is returned when a dawg is used after it has been closed.
+	ErrClosed = errors.New("dawg: closed")
+
+	// ErrByteRegexp is returned when a dawg of bytes is searched with a
+	// regular expression that does not say which bytes it matches, such as
+	// a class of some characters outside ASCII.
+	ErrByteRegexp = errors.New("dawg: expression has no meaning for a dawg of bytes")
+
+	// ErrTruncated is returned when a file is shorter than its header says
+	// it should be.
+	ErrTruncated = errors.New("dawg: truncated file")
+)
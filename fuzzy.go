@@ -0,0 +1,87 @@
+package dawg
+
+// FindWithinDistance returns all words that can be made from the input using
+// at most maxEdits insertions, deletions or substitutions of a character.
+// The results are in alphabetical order, and the Distance of each is the
+// smallest number of edits needed.
+func (d *dawg) FindWithinDistance(input string, maxEdits int) []FindResult {
+	return d.findWithinDistance(input, maxEdits, false)
+}
+
+// FindWithinDamerauDistance is like FindWithinDistance, except that swapping
+// two adjacent characters also counts as a single edit.
+func (d *dawg) FindWithinDamerauDistance(input string, maxEdits int) []FindResult {
+	return d.findWithinDistance(input, maxEdits, true)
+}
+
+func (d *dawg) findWithinDistance(input string, maxEdits int, transpose bool) []FindResult {
+	if maxEdits < 0 {
+		return nil
+	}
+
+	if !d.begin() {
+		return nil
+	}
+	defer d.release()
+
+	target := d.letters(input)
+	n := len(target)
+
+	// This simulates a Levenshtein automaton for the target. As the graph is
+	// walked, rows[i][j] holds the edit distance between the first i
+	// characters of the current prefix and the first j characters of the
+	// target. Rows are reused as the enumeration backtracks.
+	var rows [][]int
+	var results []FindResult
+
+	r := newBitSeeker(d.r)
+	defer d.addCacheStats(&r)
+	d.enumerate(&r, 0, rootNode, nil, func(index int, word []rune, final bool) EnumerationResult {
+		depth := len(word)
+		for len(rows) <= depth {
+			rows = append(rows, make([]int, n+1))
+		}
+
+		row := rows[depth]
+		best := depth
+		row[0] = depth
+		if depth == 0 {
+			for j := range row {
+				row[j] = j
+			}
+		} else {
+			prev := rows[depth-1]
+			ch := word[depth-1]
+			for j := 1; j <= n; j++ {
+				cost := 1
+				if target[j-1] == ch {
+					cost = 0
+				}
+
+				row[j] = min(min(prev[j]+1, row[j-1]+1), prev[j-1]+cost)
+				if transpose && depth > 1 && j > 1 &&
+					ch == target[j-2] && word[depth-2] == target[j-1] {
+					row[j] = min(row[j], rows[depth-2][j-2]+1)
+				}
+
+				best = min(best, row[j])
+			}
+		}
+
+		if final && row[n] <= maxEdits {
+			results = append(results, FindResult{
+				Word:     d.wordOf(word),
+				Index:    index,
+				Distance: row[n],
+			})
+		}
+
+		// no words under this prefix can be close enough
+		if best > maxEdits {
+			return Skip
+		}
+		return Continue
+	})
+
+	return results
+}
@@ -0,0 +1,75 @@
+package dawg_test
+
+import (
+	"testing"
+
+	"github.com/smhanov/dawg"
+)
+
+func TestFindWithinDistance(t *testing.T) {
+	words := []string{
+		"",
+		"act",
+		"cat",
+		"cats",
+		"coat",
+		"cut",
+		"dog",
+		"scat",
+		"tac",
+	}
+
+	finder := createDawg(words)
+
+	results := finder.FindWithinDistance("cat", 1)
+	shouldbe := []dawg.FindResult{
+		{Word: "cat", Index: 2, Distance: 0},
+		{Word: "cats", Index: 3, Distance: 1},
+		{Word: "coat", Index: 4, Distance: 1},
+		{Word: "cut", Index: 5, Distance: 1},
+		{Word: "scat", Index: 7, Distance: 1},
+	}
+	checkResults(t, results, shouldbe)
+
+	// act is two substitutions away, but only one transposition
+	results = finder.FindWithinDamerauDistance("cta", 1)
+	shouldbe = []dawg.FindResult{
+		{Word: "cat", Index: 2, Distance: 1},
+	}
+	checkResults(t, results, shouldbe)
+
+	results = finder.FindWithinDistance("cta", 2)
+	shouldbe = []dawg.FindResult{
+		{Word: "act", Index: 1, Distance: 2},
+		{Word: "cat", Index: 2, Distance: 2},
+		{Word: "cats", Index: 3, Distance: 2},
+		{Word: "coat", Index: 4, Distance: 2},
+		{Word: "cut", Index: 5, Distance: 2},
+		{Word: "tac", Index: 8, Distance: 2},
+	}
+	checkResults(t, results, shouldbe)
+
+	results = finder.FindWithinDistance("ox", 1)
+	checkResults(t, results, nil)
+
+	results = finder.FindWithinDistance("a", 1)
+	shouldbe = []dawg.FindResult{
+		{Word: "", Index: 0, Distance: 1},
+	}
+	checkResults(t, results, shouldbe)
+}
+
+func checkResults(t *testing.T, results, shouldbe []dawg.FindResult) {
+	t.Helper()
+	if len(results) != len(shouldbe) {
+		t.Errorf("Got %v but should be %v", results, shouldbe)
+		return
+	}
+
+	for i, result := range results {
+		if result != shouldbe[i] {
+			t.Errorf("Got %v but should be %v", results, shouldbe)
+			break
+		}
+	}
+}
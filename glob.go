@@ -0,0 +1,330 @@
+package dawg
+
+import "sort"
+
+const (
+	globLiteral = iota
+	globAny
+	globStar
+	globClass
+)
+
+type runeRange struct {
+	lo, hi rune
+}
+
+// globToken is one element of a compiled pattern
+type globToken struct {
+	kind   int
+	ch     rune
+	ranges []runeRange
+	negate bool
+}
+
+func (t globToken) matches(ch rune) bool {
+	switch t.kind {
+	case globLiteral:
+		return ch == t.ch
+	case globClass:
+		for _, r := range t.ranges {
+			if ch >= r.lo && ch <= r.hi {
+				return !t.negate
+			}
+		}
+		return t.negate
+	}
+	return true
+}
+
+// parseGlob splits a pattern into tokens. Malformed classes are treated as
+// literal characters. In a dawg of bytes, each byte of the pattern is a
+// character.
+func (d *dawg) parseGlob(pattern string) []globToken {
+	var tokens []globToken
+	for i := 0; i < len(pattern); {
+		ch, size := d.nextLetter(pattern[i:])
+		i += size
+		switch ch {
+		case '?':
+			tokens = append(tokens, globToken{kind: globAny})
+		case '*':
+			// consecutive stars are the same as one
+			if len(tokens) == 0 || tokens[len(tokens)-1].kind != globStar {
+				tokens = append(tokens, globToken{kind: globStar})
+			}
+		case '[':
+			if token, n, ok := d.parseClass(pattern[i:]); ok {
+				tokens = append(tokens, token)
+				i += n
+			} else {
+				tokens = append(tokens, globToken{kind: globLiteral, ch: ch})
+			}
+		case '\\':
+			if i < len(pattern) {
+				ch, size = d.nextLetter(pattern[i:])
+				i += size
+			}
+			tokens = append(tokens, globToken{kind: globLiteral, ch: ch})
+		default:
+			tokens = append(tokens, globToken{kind: globLiteral, ch: ch})
+		}
+	}
+	return tokens
+}
+
+// parseClass parses a character class following its opening '['. It returns
+// the number of bytes used, including the closing ']'.
+func (d *dawg) parseClass(pattern string) (globToken, int, bool) {
+	token := globToken{kind: globClass}
+	i := 0
+	if i < len(pattern) && (pattern[i] == '!' || pattern[i] == '^') {
+		token.negate = true
+		i++
+	}
+
+	first := true
+	for i < len(pattern) {
+		lo, size := d.nextLetter(pattern[i:])
+		if lo == ']' && !first {
+			return token, i + 1, true
+		}
+		first = false
+		i += size
+
+		if lo == '\\' && i < len(pattern) {
+			lo, size = d.nextLetter(pattern[i:])
+			i += size
+		}
+
+		hi := lo
+		if i+1 < len(pattern) && pattern[i] == '-' && pattern[i+1] != ']' {
+			hi, size = d.nextLetter(pattern[i+1:])
+			i += 1 + size
+			if hi == '\\' && i < len(pattern) {
+				hi, size = d.nextLetter(pattern[i:])
+				i += size
+			}
+		}
+
+		if lo <= hi {
+			token.ranges = append(token.ranges, runeRange{lo, hi})
+		}
+	}
+
+	return token, 0, false
+}
+
+// globMatcher walks the dawg with the set of pattern positions that can be
+// reached by each prefix.
+type globMatcher struct {
+	d      *dawg
+	r      bitSeeker
+	tokens []globToken
+	fn     EnumFn
+	runes  []rune
+}
+
+// Match calls fn for each word in the dawg that matches the pattern, in
+// alphabetical order. In the pattern, '?' matches any character, '*' matches
+// any number of characters, and [...] matches one character from a class such
+// as [aeiou] or [a-z]. A class beginning with '!' or '^' matches any character
+// that is not in it. A backslash matches the next character literally. In a
+// dawg of bytes, each byte of the pattern is one character.
+//
+// The final argument of fn is always true. If fn returns Skip, the longer
+// words beginning with the matched word are skipped.
+func (d *dawg) Match(pattern string, fn EnumFn) {
+	d.checkFinished()
+	if !d.begin() {
+		return
+	}
+	defer d.release()
+
+	m := &globMatcher{
+		d:      d,
+		r:      newBitSeeker(d.r),
+		tokens: d.parseGlob(pattern),
+		fn:     fn,
+	}
+	defer d.addCacheStats(&m.r)
+
+	m.match(rootNode, d.hasEmptyWord, 0, m.step(nil, 0, true))
+}
+
+// step returns the positions reached from the given positions by the
+// character ch. If start is true, it returns the initial positions instead.
+func (m *globMatcher) step(states []int, ch rune, start bool) []int {
+	seen := make([]bool, len(m.tokens)+1)
+	var next []int
+
+	var add func(s int)
+	add = func(s int) {
+		if seen[s] {
+			return
+		}
+		seen[s] = true
+		next = append(next, s)
+		if s < len(m.tokens) && m.tokens[s].kind == globStar {
+			add(s + 1)
+		}
+	}
+
+	if start {
+		add(0)
+	}
+
+	for _, s := range states {
+		if s == len(m.tokens) {
+			continue
+		}
+		token := m.tokens[s]
+		if token.kind == globStar {
+			add(s)
+		} else if token.matches(ch) {
+			add(s + 1)
+		}
+	}
+
+	sort.Ints(next)
+	return next
+}
+
+// ranges returns the characters that can follow the given positions, or
+// false if any character may follow.
+func (m *globMatcher) ranges(states []int) ([]runeRange, bool) {
+	var ranges []runeRange
+	for _, s := range states {
+		if s == len(m.tokens) {
+			continue
+		}
+		token := m.tokens[s]
+		switch {
+		case token.kind == globLiteral:
+			ranges = append(ranges, runeRange{token.ch, token.ch})
+		case token.kind == globClass && !token.negate:
+			ranges = append(ranges, token.ranges...)
+		default:
+			return nil, false
+		}
+	}
+
+	return mergeRanges(ranges), true
+}
+
+// mergeRanges sorts the ranges and joins those that overlap, so that no
+// edge is followed twice.
+func mergeRanges(ranges []runeRange) []runeRange {
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].lo < ranges[j].lo
+	})
+
+	var merged []runeRange
+	for _, r := range ranges {
+		if len(merged) > 0 && r.lo <= merged[len(merged)-1].hi+1 {
+			if r.hi > merged[len(merged)-1].hi {
+				merged[len(merged)-1].hi = r.hi
+			}
+		} else {
+			merged = append(merged, r)
+		}
+	}
+
+	return merged
+}
+
+func (m *globMatcher) match(node int64, final bool, index int, states []int) EnumerationResult {
+	if final && states[len(states)-1] == len(m.tokens) {
+		result := m.fn(index, m.runes, true)
+		if result != Continue {
+			return result
+		}
+	}
+
+	ranges, limited := m.ranges(states)
+	return m.d.followEdges(&m.r, node, ranges, !limited, func(ch rune, next int64, count int, nextFinal bool) EnumerationResult {
+		nextStates := m.step(states, ch, false)
+		if len(nextStates) == 0 {
+			return Continue
+		}
+
+		m.runes = append(m.runes, ch)
+		result := m.match(next, nextFinal, index+count, nextStates)
+		m.runes = m.runes[:len(m.runes)-1]
+		return result
+	})
+}
+
+// followEdges calls fn, in order, for each edge of the node whose character
+// lies in one of the sorted ranges, or for every edge if all is true. For
+// each edge, fn receives the node it leads to, its skip count, and whether
+// that node is final. It stops and returns Stop if fn does.
+func (d *dawg) followEdges(r *bitSeeker, node int64, ranges []runeRange, all bool,
+	fn func(ch rune, next int64, count int, final bool) EnumerationResult) EnumerationResult {
+
+	if !all && len(ranges) == 0 {
+		return Continue
+	}
+
+	// the final bit of the node an edge leads to
+	isFinal := func(next int64) bool {
+		r.Seek(next, 0)
+		return r.ReadBits(1) == 1
+	}
+
+	if !all && len(ranges) == 1 && ranges[0].lo == ranges[0].hi {
+		// a single character, so search for its edge directly
+		edgeEnd, final, ok := d.getEdge(r, node, ranges[0].lo)
+		if ok && fn(ranges[0].lo, edgeEnd.node, edgeEnd.count, final) == Stop {
+			return Stop
+		}
+		return Continue
+	}
+
+	if all {
+		for _, edge := range d.getNode(r, node).edges {
+			if fn(edge.ch, edge.node, edge.count, isFinal(edge.node)) == Stop {
+				return Stop
+			}
+		}
+		return Continue
+	}
+
+	if d.cachedNode(r, node) == nil {
+		// search the edges in the file for the start of each range, and
+		// read only the edges that lie in it.
+		if d.numEdges == 0 {
+			return Continue
+		}
+
+		list := d.readEdgeList(r, node)
+
+		for _, rng := range ranges {
+			for i := d.searchEdges(r, list, rng.lo); i < list.count; i++ {
+				edge := d.edgeAt(r, list, i)
+				if edge.ch > rng.hi {
+					break
+				}
+				if fn(edge.ch, edge.node, edge.count, isFinal(edge.node)) == Stop {
+					return Stop
+				}
+			}
+		}
+		return Continue
+	}
+
+	edges := d.getNode(r, node).edges
+	for _, rng := range ranges {
+		i := sort.Search(len(edges), func(i int) bool {
+			return edges[i].ch >= rng.lo
+		})
+
+		for ; i < len(edges) && edges[i].ch <= rng.hi; i++ {
+			edge := edges[i]
+			if fn(edge.ch, edge.node, edge.count, isFinal(edge.node)) == Stop {
+				return Stop
+			}
+		}
+	}
+
+	return Continue
+}
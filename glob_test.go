@@ -0,0 +1,87 @@
+package dawg_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/smhanov/dawg"
+)
+
+func TestMatch(t *testing.T) {
+	words := []string{
+		"",
+		"axe",
+		"box",
+		"cat",
+		"coat",
+		"cot",
+		"cut",
+		"eating",
+		"exit",
+		"ing",
+		"sing",
+		"x*y",
+	}
+
+	finder := createDawg(words)
+
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"c?t", []string{"cat", "cot", "cut"}},
+		{"c*t", []string{"cat", "coat", "cot", "cut"}},
+		{"*ing", []string{"eating", "ing", "sing"}},
+		{"[aeiou]x*", []string{"axe", "exit"}},
+		{"c[a-o]t", []string{"cat", "cot"}},
+		{"c[!a-o]t", []string{"cut"}},
+		{"[^a-c]*", []string{"eating", "exit", "ing", "sing", "x*y"}},
+		{"x\\*y", []string{"x*y"}},
+		{"*", words},
+		{"", []string{""}},
+		{"???", []string{"axe", "box", "cat", "cot", "cut", "ing", "x*y"}},
+		{"c??", []string{"cat", "cot", "cut"}},
+		{"d*", nil},
+	}
+
+	for _, test := range tests {
+		var results []string
+		finder.Match(test.pattern, func(index int, word []rune, final bool) dawg.EnumerationResult {
+			if finder.IndexOf(string(word)) != index {
+				t.Errorf("Match(%q) returned %q with index %d", test.pattern, string(word), index)
+			}
+			results = append(results, string(word))
+			return dawg.Continue
+		})
+
+		if !reflect.DeepEqual(results, test.expected) {
+			t.Errorf("Match(%q) returned %v, expected %v", test.pattern, results, test.expected)
+		}
+	}
+
+	count := 0
+	finder.Match("c*", func(index int, word []rune, final bool) dawg.EnumerationResult {
+		count++
+		return dawg.Stop
+	})
+
+	if count != 1 {
+		t.Errorf("Match did not stop")
+	}
+}
+
+func BenchmarkMatchWideNode(b *testing.B) {
+	// the root has an edge for each of 20000 characters
+	var words []string
+	for ch := rune(0x4e00); ch < 0x4e00+20000; ch++ {
+		words = append(words, string(ch)+"a")
+	}
+	finder := createDawg(words)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		finder.Match("[一-丅]?", func(index int, word []rune, final bool) dawg.EnumerationResult {
+			return dawg.Continue
+		})
+	}
+}
@@ -1,5 +1,5 @@
 module github.com/smhanov/dawg
 
-go 1.15
+go 1.23
 
 require golang.org/x/exp v0.0.0-20201008143054-e3b2a7f2fdc7
@@ -0,0 +1,223 @@
+package dawg
+
+import (
+	"sort"
+	"strings"
+)
+
+// Iterator returns words from the dawg in alphabetical order. Call Next() to
+// advance to each word.
+type Iterator interface {
+	// Stop after returning at most n more words. A negative limit means
+	// there is no limit.
+	Limit(n int) Iterator
+
+	// Skip all words up to and including the cursor, which is normally the
+	// result of Cursor() from a previous iterator. It must be called before
+	// the first call to Next().
+	After(cursor string) Iterator
+
+	// Advance to the next word, returning false if there are no more.
+	Next() bool
+
+	// Returns the current word
+	Word() string
+
+	// Returns the index of the current word
+	Index() int
+
+	// Returns a cursor that can be passed to After() to resume iterating
+	// after the current word.
+	Cursor() string
+}
+
+type iteratorFrame struct {
+	edges []edgeResult
+	next  int // next edge to follow
+	index int // index of the node
+}
+
+// prefixIterator lazily walks the words beneath a prefix node using an
+// explicit stack, so that only the nodes leading to the returned words are
+// decoded.
+type prefixIterator struct {
+	d        *dawg
+	r        bitSeeker
+	prefix   string
+	after    string
+	hasAfter bool
+
+	// the cursor itself is returned, if it is a word
+	inclusive bool
+	limit     int
+	started   bool
+
+	// the node for the prefix has not yet been returned
+	pending bool
+	stack   []iteratorFrame
+	runes   []rune
+	base    int // number of runes in the prefix
+
+	word  string
+	index int
+}
+
+// WithPrefix returns an Iterator over all words that start with the given
+// prefix, in alphabetical order.
+func (d *dawg) WithPrefix(prefix string) Iterator {
+	return &prefixIterator{
+		d:      d,
+		r:      newBitSeeker(d.r),
+		prefix: prefix,
+		limit:  -1,
+	}
+}
+
+// Limit stops the iterator after at most n more words.
+func (it *prefixIterator) Limit(n int) Iterator {
+	it.limit = n
+	return it
+}
+
+// After skips all words up to and including the cursor.
+func (it *prefixIterator) After(cursor string) Iterator {
+	it.after = cursor
+	it.hasAfter = true
+	return it
+}
+
+// start descends to the prefix node and, if there is a cursor, positions the
+// stack just after it.
+func (it *prefixIterator) start() {
+	d := it.d
+	it.started = true
+	it.runes = d.letters(it.prefix)
+	it.base = len(it.runes)
+
+	if it.hasAfter && it.after >= it.prefix && !strings.HasPrefix(it.after, it.prefix) {
+		// every word with the prefix comes before the cursor.
+		return
+	}
+
+	node := int64(rootNode)
+	index := 0
+	final := d.hasEmptyWord
+	for _, letter := range it.runes {
+		edgeEnd, nodeFinal, ok := d.getEdge(&it.r, node, letter)
+		if !ok {
+			return
+		}
+		node = edgeEnd.node
+		index += edgeEnd.count
+		final = nodeFinal
+	}
+
+	it.stack = append(it.stack, iteratorFrame{
+		edges: d.getNode(&it.r, node).edges,
+		index: index,
+	})
+
+	if !it.hasAfter || it.after < it.prefix {
+		it.pending = final
+		return
+	}
+
+	// follow the cursor as far as possible. Each node along the way has been
+	// returned already, so continue from the edge after it.
+	for _, letter := range d.letters(it.after[len(it.prefix):]) {
+		top := &it.stack[len(it.stack)-1]
+		i := sort.Search(len(top.edges), func(i int) bool {
+			return top.edges[i].ch >= letter
+		})
+
+		top.next = i
+		if i == len(top.edges) || top.edges[i].ch != letter {
+			return
+		}
+
+		top.next++
+		edge := top.edges[i]
+		node := d.getNode(&it.r, edge.node)
+		it.runes = append(it.runes, letter)
+		it.stack = append(it.stack, iteratorFrame{
+			edges: node.edges,
+			index: top.index + edge.count,
+		})
+		final = node.final
+	}
+
+	// the whole cursor was found
+	it.pending = it.inclusive && final
+}
+
+// Next advances to the next word
+func (it *prefixIterator) Next() bool {
+	if it.limit == 0 {
+		return false
+	}
+
+	if !it.d.begin() {
+		return false
+	}
+	defer it.d.release()
+	defer it.d.addCacheStats(&it.r)
+
+	if !it.started {
+		it.start()
+	}
+
+	if it.pending {
+		it.pending = false
+		return it.found(it.stack[len(it.stack)-1].index)
+	}
+
+	for len(it.stack) > 0 {
+		top := &it.stack[len(it.stack)-1]
+		if top.next == len(top.edges) {
+			it.stack = it.stack[:len(it.stack)-1]
+			continue
+		}
+
+		edge := top.edges[top.next]
+		top.next++
+		index := top.index + edge.count
+		it.runes = append(it.runes[:it.base+len(it.stack)-1], edge.ch)
+
+		node := it.d.getNode(&it.r, edge.node)
+		it.stack = append(it.stack, iteratorFrame{
+			edges: node.edges,
+			index: index,
+		})
+
+		if node.final {
+			return it.found(index)
+		}
+	}
+
+	return false
+}
+
+func (it *prefixIterator) found(index int) bool {
+	it.word = it.d.wordOf(it.runes)
+	it.index = index
+	if it.limit > 0 {
+		it.limit--
+	}
+	return true
+}
+
+// Word returns the current word
+func (it *prefixIterator) Word() string {
+	return it.word
+}
+
+// Index returns the index of the current word
+func (it *prefixIterator) Index() int {
+	return it.index
+}
+
+// Cursor returns a value that can be passed to After() to continue after the
+// current word.
+func (it *prefixIterator) Cursor() string {
+	return it.word
+}
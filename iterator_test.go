@@ -0,0 +1,98 @@
+package dawg_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/smhanov/dawg"
+)
+
+func collect(it dawg.Iterator) []dawg.FindResult {
+	var results []dawg.FindResult
+	for it.Next() {
+		results = append(results, dawg.FindResult{Word: it.Word(), Index: it.Index()})
+	}
+	return results
+}
+
+func TestWithPrefix(t *testing.T) {
+	words := []string{
+		"",
+		"blip",
+		"cat",
+		"catnip",
+		"cats",
+		"cut",
+		"dog",
+	}
+
+	finder := createDawg(words)
+
+	checkResults(t, collect(finder.WithPrefix("cat")), []dawg.FindResult{
+		{Word: "cat", Index: 2},
+		{Word: "catnip", Index: 3},
+		{Word: "cats", Index: 4},
+	})
+
+	checkResults(t, collect(finder.WithPrefix("c").Limit(2)), []dawg.FindResult{
+		{Word: "cat", Index: 2},
+		{Word: "catnip", Index: 3},
+	})
+
+	checkResults(t, collect(finder.WithPrefix("c").After("catnip")), []dawg.FindResult{
+		{Word: "cats", Index: 4},
+		{Word: "cut", Index: 5},
+	})
+
+	checkResults(t, collect(finder.WithPrefix("c").After("catn")), []dawg.FindResult{
+		{Word: "catnip", Index: 3},
+		{Word: "cats", Index: 4},
+		{Word: "cut", Index: 5},
+	})
+
+	checkResults(t, collect(finder.WithPrefix("c").After("b")), []dawg.FindResult{
+		{Word: "cat", Index: 2},
+		{Word: "catnip", Index: 3},
+		{Word: "cats", Index: 4},
+		{Word: "cut", Index: 5},
+	})
+
+	checkResults(t, collect(finder.WithPrefix("c").After("d")), nil)
+	checkResults(t, collect(finder.WithPrefix("x")), nil)
+
+	if len(collect(finder.WithPrefix(""))) != len(words) {
+		t.Errorf("WithPrefix(\"\") did not return all words")
+	}
+}
+
+func TestWithPrefixPaging(t *testing.T) {
+	var words []string
+	for i := 0; i < 100; i++ {
+		words = append(words, fmt.Sprintf("page%03d", i))
+	}
+	finder := createDawg(words)
+
+	var all []string
+	cursor := ""
+	for page := 0; ; page++ {
+		it := finder.WithPrefix("page").Limit(7)
+		if page > 0 {
+			it.After(cursor)
+		}
+
+		n := 0
+		for it.Next() {
+			all = append(all, it.Word())
+			cursor = it.Cursor()
+			n++
+		}
+
+		if n == 0 {
+			break
+		}
+	}
+
+	if fmt.Sprint(all) != fmt.Sprint(words) {
+		t.Errorf("Paging returned %v", all)
+	}
+}
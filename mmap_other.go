@@ -0,0 +1,15 @@
+//go:build !linux && !darwin
+
+package dawg
+
+import "golang.org/x/exp/mmap"
+
+// openMapped opens the file with mmap. Its bytes cannot be read in place, so
+// the aligned layout is read through a bitSeeker, like the packed one.
+func openMapped(filename string) (mappedReader, error) {
+	f, err := mmap.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,78 @@
+//go:build linux || darwin
+
+package dawg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// mappedFile is a file mapped into memory. Unlike mmap.ReaderAt, its bytes
+// can be read in place, so that the aligned layout does not copy them.
+type mappedFile struct {
+	data []byte
+}
+
+// openMapped maps the file into memory for reading
+func openMapped(filename string) (mappedReader, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	size := fi.Size()
+	if size == 0 {
+		return &mappedFile{}, nil
+	} else if size != int64(int(size)) {
+		return nil, fmt.Errorf("dawg: file %q is too large to map", filename)
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &mappedFile{data: data}
+	runtime.SetFinalizer(m, (*mappedFile).Close)
+	return m, nil
+}
+
+// Bytes returns the contents of the file. They are only valid until Close.
+func (m *mappedFile) Bytes() []byte {
+	return m.data
+}
+
+// ReadAt implements io.ReaderAt
+func (m *mappedFile) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 || off > int64(len(m.data)) {
+		return 0, errors.New("dawg: invalid offset")
+	}
+
+	n := copy(p, m.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Close unmaps the file
+func (m *mappedFile) Close() error {
+	if m.data == nil {
+		return nil
+	}
+
+	data := m.data
+	m.data = nil
+	runtime.SetFinalizer(m, nil)
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,114 @@
+package dawg
+
+// rank returns the number of words that sort before the word, and whether
+// the word is in the dawg.
+func (d *dawg) rank(word string) (int, bool) {
+	node := int64(rootNode)
+	total := d.numAdded
+	final := d.hasEmptyWord
+	r := newBitSeeker(d.r)
+	defer d.addCacheStats(&r)
+
+	index := 0
+	for pos := 0; pos < len(word); {
+		letter, size := d.nextLetter(word[pos:])
+		pos += size
+
+		span := d.getEdgeSpan(&r, node, total, letter)
+		index += span.before
+		if !span.ok {
+			return index, false
+		}
+
+		node = span.node
+		total = span.through - span.before
+		final = span.final
+	}
+
+	// the words beneath the node are longer, so they sort after it.
+	return index, final
+}
+
+// Rank returns the number of words that sort before s, whether or not s is
+// in the dawg. If s is in the dawg, this is its index.
+func (d *dawg) Rank(s string) int {
+	d.checkFinished()
+	if !d.begin() {
+		return 0
+	}
+	defer d.release()
+
+	index, _ := d.rank(s)
+	return index
+}
+
+// wordAt returns the word with the given index, or false if there is no such
+// word.
+func (d *dawg) wordAt(index int) (FindResult, bool) {
+	if index < 0 || index >= d.numAdded {
+		return FindResult{}, false
+	}
+
+	r := newBitSeeker(d.r)
+	defer d.addCacheStats(&r)
+	word, _ := d.atIndex(&r, rootNode, 0, index, nil)
+	return FindResult{Word: word, Index: index}, true
+}
+
+// Ceiling returns the first word that is greater than or equal to the given
+// word, or false if there is none.
+func (d *dawg) Ceiling(word string) (FindResult, bool) {
+	d.checkFinished()
+	if !d.begin() {
+		return FindResult{}, false
+	}
+	defer d.release()
+
+	index, _ := d.rank(word)
+	return d.wordAt(index)
+}
+
+// Floor returns the last word that is less than or equal to the given word,
+// or false if there is none.
+func (d *dawg) Floor(word string) (FindResult, bool) {
+	d.checkFinished()
+	if !d.begin() {
+		return FindResult{}, false
+	}
+	defer d.release()
+
+	index, found := d.rank(word)
+	if found {
+		return FindResult{Word: word, Index: index}, true
+	}
+	return d.wordAt(index - 1)
+}
+
+// Next returns the first word that is greater than the given word, or false
+// if there is none.
+func (d *dawg) Next(word string) (FindResult, bool) {
+	d.checkFinished()
+	if !d.begin() {
+		return FindResult{}, false
+	}
+	defer d.release()
+
+	index, found := d.rank(word)
+	if found {
+		index++
+	}
+	return d.wordAt(index)
+}
+
+// Prev returns the last word that is less than the given word, or false if
+// there is none.
+func (d *dawg) Prev(word string) (FindResult, bool) {
+	d.checkFinished()
+	if !d.begin() {
+		return FindResult{}, false
+	}
+	defer d.release()
+
+	index, _ := d.rank(word)
+	return d.wordAt(index - 1)
+}
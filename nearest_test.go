@@ -0,0 +1,75 @@
+package dawg_test
+
+import (
+	"testing"
+
+	"github.com/smhanov/dawg"
+)
+
+func TestNearest(t *testing.T) {
+	words := []string{"blip", "cat", "catnip", "cats", "dog"}
+	finder := createDawg(words)
+
+	tests := []struct {
+		word                       string
+		ceiling, floor, next, prev string
+	}{
+		{"", "blip", "", "blip", ""},
+		{"blip", "blip", "blip", "cat", ""},
+		{"c", "cat", "blip", "cat", "blip"},
+		{"cat", "cat", "cat", "catnip", "blip"},
+		{"catapult", "catnip", "cat", "catnip", "cat"},
+		{"catz", "dog", "cats", "dog", "cats"},
+		{"dog", "dog", "dog", "", "cats"},
+		{"zebra", "", "dog", "", "dog"},
+	}
+
+	check := func(name, word string, result dawg.FindResult, ok bool, expected string) {
+		if expected == "" {
+			if ok {
+				t.Errorf("%s(%q) returned %v, expected nothing", name, word, result)
+			}
+		} else if !ok || result.Word != expected || words[result.Index] != expected {
+			t.Errorf("%s(%q) returned %v, expected %q", name, word, result, expected)
+		}
+	}
+
+	for _, test := range tests {
+		result, ok := finder.Ceiling(test.word)
+		check("Ceiling", test.word, result, ok, test.ceiling)
+		result, ok = finder.Floor(test.word)
+		check("Floor", test.word, result, ok, test.floor)
+		result, ok = finder.Next(test.word)
+		check("Next", test.word, result, ok, test.next)
+		result, ok = finder.Prev(test.word)
+		check("Prev", test.word, result, ok, test.prev)
+	}
+}
+
+func TestRank(t *testing.T) {
+	words := []string{"", "blip", "cat", "catnip", "cats", "dog"}
+	finder := createDawg(words)
+
+	tests := []struct {
+		word string
+		rank int
+	}{
+		{"", 0},
+		{"a", 1},
+		{"blip", 1},
+		{"blipper", 2},
+		{"cat", 2},
+		{"cata", 3},
+		{"cats", 4},
+		{"d", 5},
+		{"dog", 5},
+		{"dogs", 6},
+		{"z", 6},
+	}
+
+	for _, test := range tests {
+		if rank := finder.Rank(test.word); rank != test.rank {
+			t.Errorf("Rank(%q) returned %d, expected %d", test.word, rank, test.rank)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package dawg
+
+// Match is a word found in a piece of text
+type Match struct {
+	Word  string
+	Index int
+
+	// Byte offset of the word in the text
+	Offset int
+}
+
+type rootStep struct {
+	edge  edgeEnd
+	final bool
+	ok    bool
+}
+
+// FindAllOccurrences returns every word in the dawg that occurs anywhere in
+// the text, ordered by offset and then by length. A word that occurs more
+// than once is returned once for each occurrence. The empty word is never
+// returned. It will panic if the dawg is not finished.
+//
+// This is not an Aho-Corasick search. The dawg has no links from one word to
+// its suffixes, so the search starts again from the root at each offset, and
+// follows the text for as long as it is a prefix of some word. The cost is
+// O(n·L) for a text of n characters, where L is the length of the longest
+// prefix followed. Only the first step from the root is remembered between
+// offsets.
+func (d *dawg) FindAllOccurrences(text string) []Match {
+	d.checkFinished()
+	if !d.begin() {
+		return nil
+	}
+	defer d.release()
+
+	var results []Match
+	r := newBitSeeker(d.r)
+	defer d.addCacheStats(&r)
+
+	// Every search begins at the root, so remember where each letter leads
+	// from there instead of decoding the root each time.
+	root := make(map[rune]rootStep)
+
+	for offset, size := 0, 0; offset < len(text); offset += size {
+		_, size = d.nextLetter(text[offset:])
+
+		node := int64(rootNode)
+		index := 0
+		for end := offset; end < len(text); {
+			letter, size := d.nextLetter(text[end:])
+
+			var step rootStep
+			if end == offset {
+				var seen bool
+				if step, seen = root[letter]; !seen {
+					step.edge, step.final, step.ok = d.getEdge(&r, node, letter)
+					root[letter] = step
+				}
+			} else {
+				step.edge, step.final, step.ok = d.getEdge(&r, node, letter)
+			}
+
+			if !step.ok {
+				break
+			}
+
+			node = step.edge.node
+			index += step.edge.count
+			end += size
+
+			if step.final {
+				results = append(results, Match{
+					Word:   text[offset:end],
+					Index:  index,
+					Offset: offset,
+				})
+			}
+		}
+	}
+
+	return results
+}
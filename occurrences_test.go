@@ -0,0 +1,40 @@
+package dawg_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/smhanov/dawg"
+)
+
+func TestFindAllOccurrences(t *testing.T) {
+	words := []string{
+		"",
+		"at",
+		"cat",
+		"catnip",
+		"nip",
+		"tn",
+		"ün",
+	}
+
+	finder := createDawg(words)
+
+	expected := []dawg.Match{
+		{Word: "cat", Index: 2, Offset: 1},
+		{Word: "catnip", Index: 3, Offset: 1},
+		{Word: "at", Index: 1, Offset: 2},
+		{Word: "tn", Index: 5, Offset: 3},
+		{Word: "nip", Index: 4, Offset: 4},
+		{Word: "ün", Index: 6, Offset: 8},
+	}
+
+	results := finder.FindAllOccurrences("ccatnip\xffün")
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("FindAllOccurrences returned %v, expected %v", results, expected)
+	}
+
+	if results := finder.FindAllOccurrences("dog"); len(results) != 0 {
+		t.Errorf("FindAllOccurrences returned %v", results)
+	}
+}
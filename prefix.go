@@ -0,0 +1,42 @@
+package dawg
+
+// PrefixRange returns the range of indexes of the words that start with the
+// prefix. Words sharing a prefix are numbered consecutively, so these are
+// the words with lo <= index < hi. It takes time proportional to the length
+// of the prefix, no matter how many words there are.
+//
+// If no words start with the prefix, ok is false and lo == hi is the index
+// that such a word would have.
+func (d *dawg) PrefixRange(prefix string) (lo, hi int, ok bool) {
+	if !d.begin() {
+		return 0, 0, false
+	}
+	defer d.release()
+
+	node := int64(rootNode)
+	total := d.numAdded
+	r := newBitSeeker(d.r)
+	defer d.addCacheStats(&r)
+
+	for pos := 0; pos < len(prefix); {
+		letter, size := d.nextLetter(prefix[pos:])
+		pos += size
+
+		span := d.getEdgeSpan(&r, node, total, letter)
+		lo += span.before
+		if !span.ok {
+			return lo, lo, false
+		}
+
+		node = span.node
+		total = span.through - span.before
+	}
+
+	return lo, lo + total, total > 0
+}
+
+// CountWithPrefix returns the number of words that start with the prefix.
+func (d *dawg) CountWithPrefix(prefix string) int {
+	lo, hi, _ := d.PrefixRange(prefix)
+	return hi - lo
+}
@@ -0,0 +1,49 @@
+package dawg_test
+
+import (
+	"testing"
+)
+
+func TestPrefixRange(t *testing.T) {
+	words := []string{
+		"",
+		"blip",
+		"cat",
+		"catnip",
+		"cats",
+		"cut",
+		"dog",
+	}
+
+	finder := createDawg(words)
+
+	tests := []struct {
+		prefix string
+		lo, hi int
+		ok     bool
+	}{
+		{"", 0, 7, true},
+		{"c", 2, 6, true},
+		{"cat", 2, 5, true},
+		{"catn", 3, 4, true},
+		{"cats", 4, 5, true},
+		{"cu", 5, 6, true},
+		{"d", 6, 7, true},
+		{"a", 1, 1, false},
+		{"cb", 5, 5, false},
+		{"catz", 5, 5, false},
+		{"e", 7, 7, false},
+	}
+
+	for _, test := range tests {
+		lo, hi, ok := finder.PrefixRange(test.prefix)
+		if lo != test.lo || hi != test.hi || ok != test.ok {
+			t.Errorf("PrefixRange(%q) returned %d, %d, %v; expected %d, %d, %v",
+				test.prefix, lo, hi, ok, test.lo, test.hi, test.ok)
+		}
+
+		if count := finder.CountWithPrefix(test.prefix); count != test.hi-test.lo {
+			t.Errorf("CountWithPrefix(%q) returned %d", test.prefix, count)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package dawg
+
+import (
+	"regexp/syntax"
+	"unicode/utf8"
+)
+
+// regexpMatcher runs a compiled regular expression over the dawg, keeping
+// the set of instructions that each prefix can reach.
+type regexpMatcher struct {
+	d     *dawg
+	r     bitSeeker
+	prog  *syntax.Prog
+	fn    EnumFn
+	runes []rune
+	seen  []bool
+}
+
+// MatchRegexp calls fn for each word in the dawg that the regular expression
+// matches in full, in alphabetical order. The final argument of fn is always
+// true. If fn returns Skip, the longer words beginning with the matched word
+// are skipped. An error is returned if the expression cannot be compiled, or
+// ErrClosed if the dawg has been closed.
+//
+// In a dawg of bytes, a literal character outside ASCII matches its UTF-8
+// encoding, and '.' or a class matches one byte. ErrByteRegexp is returned
+// if the expression ignores the case of a character outside ASCII, or has a
+// class with some but not all of the characters from \x80 to \xff, since
+// it is not clear which bytes those should match.
+//
+// Only the branches of the dawg that the expression can still match are
+// explored, so a search for words with a literal prefix or a small set of
+// characters does not read the whole dawg.
+func (d *dawg) MatchRegexp(re *syntax.Regexp, fn EnumFn) error {
+	d.checkFinished()
+	if d.byteKeys {
+		var ok bool
+		if re, ok = byteRegexp(re); !ok {
+			return ErrByteRegexp
+		}
+	}
+
+	prog, err := syntax.Compile(re.Simplify())
+	if err != nil {
+		return err
+	}
+
+	if err := d.acquire(); err != nil {
+		return err
+	}
+	defer d.release()
+
+	m := &regexpMatcher{
+		d:    d,
+		r:    newBitSeeker(d.r),
+		prog: prog,
+		fn:   fn,
+		seen: make([]bool, len(prog.Inst)),
+	}
+	defer d.addCacheStats(&m.r)
+
+	m.match(rootNode, d.hasEmptyWord, 0, []uint32{uint32(prog.Start)})
+	return nil
+}
+
+// byteRegexp returns a copy of the expression for a dawg of bytes, in which
+// each literal character outside ASCII is replaced by its UTF-8 encoding.
+// It returns false if the expression has no clear meaning for bytes.
+func byteRegexp(re *syntax.Regexp) (*syntax.Regexp, bool) {
+	copied := *re
+	switch re.Op {
+	case syntax.OpLiteral:
+		copied.Rune = nil
+		for _, ch := range re.Rune {
+			if ch < utf8.RuneSelf {
+				copied.Rune = append(copied.Rune, ch)
+				continue
+			} else if re.Flags&syntax.FoldCase != 0 {
+				return nil, false
+			}
+
+			for _, b := range []byte(string(ch)) {
+				copied.Rune = append(copied.Rune, rune(b))
+			}
+		}
+	case syntax.OpCharClass:
+		if n := classCount(re.Rune, 0x80, 0xff); n != 0 && n != 0x80 {
+			return nil, false
+		}
+	}
+
+	copied.Sub = nil
+	for _, sub := range re.Sub {
+		sub, ok := byteRegexp(sub)
+		if !ok {
+			return nil, false
+		}
+		copied.Sub = append(copied.Sub, sub)
+	}
+	return &copied, true
+}
+
+// classCount returns how many of the characters from lo to hi are in the
+// ranges of a class.
+func classCount(ranges []rune, lo, hi rune) int {
+	n := 0
+	for i := 0; i+1 < len(ranges); i += 2 {
+		from, to := ranges[i], ranges[i+1]
+		if from < lo {
+			from = lo
+		}
+		if to > hi {
+			to = hi
+		}
+		if from <= to {
+			n += int(to - from + 1)
+		}
+	}
+	return n
+}
+
+// expand follows the instructions that do not consume a character, starting
+// from the given ones, and returns the instructions reached which do. The
+// context describes the characters on either side of the current position,
+// for instructions such as \b. If the match instruction is reached, the
+// second result is true.
+func (m *regexpMatcher) expand(pcs []uint32, context syntax.EmptyOp) ([]uint32, bool) {
+	for i := range m.seen {
+		m.seen[i] = false
+	}
+
+	var result []uint32
+	matched := false
+	stack := append([]uint32{}, pcs...)
+	for len(stack) > 0 {
+		pc := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if m.seen[pc] {
+			continue
+		}
+		m.seen[pc] = true
+
+		inst := &m.prog.Inst[pc]
+		switch inst.Op {
+		case syntax.InstAlt, syntax.InstAltMatch:
+			stack = append(stack, inst.Arg, inst.Out)
+		case syntax.InstCapture, syntax.InstNop:
+			stack = append(stack, inst.Out)
+		case syntax.InstEmptyWidth:
+			if syntax.EmptyOp(inst.Arg)&^context == 0 {
+				stack = append(stack, inst.Out)
+			}
+		case syntax.InstMatch:
+			matched = true
+		case syntax.InstRune, syntax.InstRune1, syntax.InstRuneAny, syntax.InstRuneAnyNotNL:
+			result = append(result, pc)
+		}
+	}
+
+	return result, matched
+}
+
+// ranges returns the characters that the instructions can consume, or false
+// if they cannot easily be listed. Assertions are assumed to succeed, so some
+// of the characters may not lead anywhere.
+func (m *regexpMatcher) ranges(pcs []uint32, prev rune) ([]runeRange, bool) {
+	context := syntax.EmptyOpContext(prev, 'a') | syntax.EmptyOpContext(prev, ' ') |
+		syntax.EmptyOpContext(prev, '\n')
+	insts, _ := m.expand(pcs, context)
+
+	var ranges []runeRange
+	for _, pc := range insts {
+		inst := &m.prog.Inst[pc]
+		if inst.Op != syntax.InstRune && inst.Op != syntax.InstRune1 ||
+			syntax.Flags(inst.Arg)&syntax.FoldCase != 0 {
+			return nil, false
+		}
+
+		if len(inst.Rune) == 1 {
+			ranges = append(ranges, runeRange{inst.Rune[0], inst.Rune[0]})
+			continue
+		}
+
+		for i := 0; i+1 < len(inst.Rune); i += 2 {
+			ranges = append(ranges, runeRange{inst.Rune[i], inst.Rune[i+1]})
+		}
+	}
+
+	return mergeRanges(ranges), true
+}
+
+func (m *regexpMatcher) match(node int64, final bool, index int, pcs []uint32) EnumerationResult {
+	prev := rune(-1)
+	if len(m.runes) > 0 {
+		prev = m.runes[len(m.runes)-1]
+	}
+
+	if final {
+		if _, matched := m.expand(pcs, syntax.EmptyOpContext(prev, -1)); matched {
+			result := m.fn(index, m.runes, true)
+			if result != Continue {
+				return result
+			}
+		}
+	}
+
+	ranges, limited := m.ranges(pcs, prev)
+	return m.d.followEdges(&m.r, node, ranges, !limited, func(ch rune, next int64, count int, nextFinal bool) EnumerationResult {
+		insts, _ := m.expand(pcs, syntax.EmptyOpContext(prev, ch))
+
+		var nextPcs []uint32
+		for _, pc := range insts {
+			inst := &m.prog.Inst[pc]
+			if inst.MatchRune(ch) {
+				nextPcs = append(nextPcs, inst.Out)
+			}
+		}
+
+		if len(nextPcs) == 0 {
+			return Continue
+		}
+
+		m.runes = append(m.runes, ch)
+		result := m.match(next, nextFinal, index+count, nextPcs)
+		m.runes = m.runes[:len(m.runes)-1]
+		return result
+	})
+}
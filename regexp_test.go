@@ -0,0 +1,65 @@
+package dawg_test
+
+import (
+	"reflect"
+	"regexp/syntax"
+	"testing"
+
+	"github.com/smhanov/dawg"
+)
+
+func TestMatchRegexp(t *testing.T) {
+	words := []string{
+		"",
+		"Cat",
+		"ab",
+		"abab",
+		"cat",
+		"cats",
+		"cot",
+		"dog",
+		"dogs",
+		"x y",
+	}
+
+	finder := createDawg(words)
+
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"c[ao]t", []string{"cat", "cot"}},
+		{"(?i)cat", []string{"Cat", "cat"}},
+		{"(ab)+", []string{"ab", "abab"}},
+		{"(cat|dog)s?", []string{"cat", "cats", "dog", "dogs"}},
+		{".*s", []string{"cats", "dogs"}},
+		{"x\\b.\\by", []string{"x y"}},
+		{"\\w*", []string{"", "Cat", "ab", "abab", "cat", "cats", "cot", "dog", "dogs"}},
+		{"", []string{""}},
+		{"ca", nil},
+	}
+
+	for _, test := range tests {
+		re, err := syntax.Parse(test.pattern, syntax.Perl)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var results []string
+		err = finder.MatchRegexp(re, func(index int, word []rune, final bool) dawg.EnumerationResult {
+			if finder.IndexOf(string(word)) != index {
+				t.Errorf("MatchRegexp(%q) returned %q with index %d", test.pattern, string(word), index)
+			}
+			results = append(results, string(word))
+			return dawg.Continue
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(results, test.expected) {
+			t.Errorf("MatchRegexp(%q) returned %v, expected %v", test.pattern, results, test.expected)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package dawg
+
+// register holds the minimized nodes of a dawg that is being built, so that
+// equivalent nodes can be found and shared. It is an open addressing hash
+// table of node ids. The hash of a node is calculated from its structure, so
+// no keys are stored, and nodes with the same hash are compared in full.
+type register struct {
+	slots []int // node id + 1, or 0 if the slot is empty
+	count int
+}
+
+// hashNode returns the hash of a node's finality and edges
+func (d *dawg) hashNode(id int) uint64 {
+	node := &d.nodes[id]
+	h := uint64(14695981039346656037)
+	if node.final {
+		h = 1099511628211
+	}
+
+	for _, edge := range node.edges {
+		h = (h ^ uint64(edge.ch)) * 1099511628211
+		h = (h ^ uint64(edge.node)) * 1099511628211
+	}
+
+	// mix the bits, so that the low bits used for the slot depend on all
+	// of them
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	return h
+}
+
+// sameNode returns true if the nodes have the same finality and edges
+func (d *dawg) sameNode(a, b int) bool {
+	x, y := &d.nodes[a], &d.nodes[b]
+	if x.final != y.final || len(x.edges) != len(y.edges) {
+		return false
+	}
+
+	for i := range x.edges {
+		if x.edges[i] != y.edges[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// findOrAdd returns a registered node that is equivalent to the given one.
+// If there is none, the node is registered and returned.
+func (d *dawg) findOrAdd(id int) int {
+	reg := &d.register
+	if (reg.count+1)*4 > len(reg.slots)*3 {
+		d.growRegister()
+	}
+
+	mask := uint64(len(reg.slots) - 1)
+	for slot := d.hashNode(id) & mask; ; slot = (slot + 1) & mask {
+		existing := reg.slots[slot]
+		if existing == 0 {
+			reg.slots[slot] = id + 1
+			reg.count++
+			return id
+		}
+
+		if d.sameNode(existing-1, id) {
+			return existing - 1
+		}
+	}
+}
+
+// growRegister doubles the number of slots in the register
+func (d *dawg) growRegister() {
+	reg := &d.register
+	size := len(reg.slots) * 2
+	if size == 0 {
+		size = 1024
+	}
+
+	old := reg.slots
+	reg.slots = make([]int, size)
+	mask := uint64(size - 1)
+	for _, existing := range old {
+		if existing == 0 {
+			continue
+		}
+
+		slot := d.hashNode(existing-1) & mask
+		for reg.slots[slot] != 0 {
+			slot = (slot + 1) & mask
+		}
+		reg.slots[slot] = existing
+	}
+}
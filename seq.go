@@ -0,0 +1,65 @@
+package dawg
+
+import "iter"
+
+// Prefix is a prefix of one or more words in the dawg
+type Prefix struct {
+	Word string
+
+	// Index of the first word that starts with the prefix
+	Index int
+
+	// The prefix is itself a word
+	Final bool
+}
+
+// All returns an iterator over the index and text of every word in the dawg,
+// in alphabetical order. The words are new strings which may be kept after
+// the loop. It will panic if the dawg is not finished.
+func (d *dawg) All() iter.Seq2[int, string] {
+	d.checkFinished()
+	return d.Range("", "")
+}
+
+// Prefixes returns an iterator over every prefix of the words in the dawg,
+// in the same order as Enumerate. Unlike the slice passed to an EnumFn, the
+// Word of each Prefix is a new string which may be kept after the loop.
+func (d *dawg) Prefixes() iter.Seq[Prefix] {
+	d.checkFinished()
+	return func(yield func(Prefix) bool) {
+		d.Enumerate(func(index int, word []rune, final bool) EnumerationResult {
+			if !yield(Prefix{d.wordOf(word), index, final}) {
+				return Stop
+			}
+			return Continue
+		})
+	}
+}
+
+// Range returns an iterator over the index and text of the words from
+// "from" up to but not including "to", in alphabetical order. If to is empty,
+// there is no upper limit. The words are new strings which may be kept after
+// the loop.
+func (d *dawg) Range(from, to string) iter.Seq2[int, string] {
+	d.checkFinished()
+	return func(yield func(int, string) bool) {
+		it := &prefixIterator{
+			d:         d,
+			r:         newBitSeeker(d.r),
+			limit:     -1,
+			after:     from,
+			hasAfter:  true,
+			inclusive: true,
+		}
+
+		for it.Next() {
+			if to != "" && it.word >= to {
+				return
+			}
+
+			if !yield(it.index, it.word) {
+				return
+			}
+		}
+	}
+}
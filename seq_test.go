@@ -0,0 +1,99 @@
+package dawg_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/smhanov/dawg"
+)
+
+func TestAll(t *testing.T) {
+	words := []string{"", "blip", "cat", "catnip", "cats"}
+	finder := createDawg(words)
+
+	var found []string
+	for index, word := range finder.All() {
+		if index != len(found) {
+			t.Errorf("All returned %q with index %d", word, index)
+		}
+		found = append(found, word)
+	}
+
+	if !reflect.DeepEqual(found, words) {
+		t.Errorf("All returned %v", found)
+	}
+
+	found = nil
+	for _, word := range finder.All() {
+		if word == "cat" {
+			break
+		}
+		found = append(found, word)
+	}
+
+	if !reflect.DeepEqual(found, words[:2]) {
+		t.Errorf("All did not stop, returned %v", found)
+	}
+}
+
+func TestPrefixesSeq(t *testing.T) {
+	finder := createDawg([]string{"ab", "b"})
+
+	expected := []dawg.Prefix{
+		{Word: "", Index: 0, Final: false},
+		{Word: "a", Index: 0, Final: false},
+		{Word: "ab", Index: 0, Final: true},
+		{Word: "b", Index: 1, Final: true},
+	}
+
+	var found []dawg.Prefix
+	for prefix := range finder.Prefixes() {
+		found = append(found, prefix)
+	}
+
+	if !reflect.DeepEqual(found, expected) {
+		t.Errorf("Prefixes returned %v, expected %v", found, expected)
+	}
+
+	found = nil
+	for prefix := range finder.Prefixes() {
+		found = append(found, prefix)
+		break
+	}
+
+	if len(found) != 1 {
+		t.Errorf("Prefixes did not stop")
+	}
+}
+
+func TestRange(t *testing.T) {
+	words := []string{"", "blip", "cat", "catnip", "cats", "dog"}
+	finder := createDawg(words)
+
+	tests := []struct {
+		from, to string
+		expected []string
+	}{
+		{"", "", words},
+		{"cat", "cats", []string{"cat", "catnip"}},
+		{"ca", "d", []string{"cat", "catnip", "cats"}},
+		{"catn", "", []string{"catnip", "cats", "dog"}},
+		{"b", "blip", nil},
+		{"dogs", "", nil},
+		{"", "a", []string{""}},
+	}
+
+	for _, test := range tests {
+		var found []string
+		for index, word := range finder.Range(test.from, test.to) {
+			if finder.IndexOf(word) != index {
+				t.Errorf("Range returned %q with index %d", word, index)
+			}
+			found = append(found, word)
+		}
+
+		if !reflect.DeepEqual(found, test.expected) {
+			t.Errorf("Range(%q, %q) returned %v, expected %v", test.from, test.to, found, test.expected)
+		}
+	}
+}
@@ -0,0 +1,410 @@
+package dawg
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// entryOverhead is the approximate memory used by each pending word, in
+// addition to its characters.
+const entryOverhead = 32
+
+type unsortedEntry struct {
+	word  string
+	value uint64
+}
+
+// unsortedBuilder accepts words in any order. Words are kept in memory until
+// the memory limit is reached. Then they are sorted and written to a
+// temporary file as a run. When Finish() is called, the runs are merged and
+// added to a dawg in alphabetical order.
+type unsortedBuilder struct {
+	memoryLimit int
+	used        int
+	pending     []unsortedEntry
+	runs        []*os.File
+	hasValues   bool
+	byteKeys    bool // the words were added with AddBytes
+	builder     Builder
+	err         error // error from writing or merging the runs
+}
+
+// NewUnsortedBuilder creates a Builder which accepts words in any order,
+// and may contain duplicates. If a word is added more than once, the value
+// from the last call is kept. Once the words held in memory take up about
+// memoryLimit bytes, they are sorted and written to a temporary file. If
+// memoryLimit is zero, all words are held in memory. The temporary files are
+// removed by Finish, or by Abort if the dawg is not needed.
+//
+// Because words are sorted before they are added, the index of each word is
+// its position in alphabetical order rather than the order it was added.
+func NewUnsortedBuilder(memoryLimit int) Builder {
+	return &unsortedBuilder{
+		memoryLimit: memoryLimit,
+	}
+}
+
+// CanAdd returns true if the dawg has not been finished, and no temporary
+// file has failed.
+func (u *unsortedBuilder) CanAdd(word string) bool {
+	return u.builder == nil && u.err == nil
+}
+
+// Add adds a word to the builder. It will panic if the builder is finished
+// or a temporary file cannot be written.
+func (u *unsortedBuilder) Add(word string) {
+	if err := u.TryAdd(word); err != nil {
+		panic(err)
+	}
+}
+
+// AddWithValue adds a word to the builder, along with a value that can be
+// retrieved using Finder.Value().
+func (u *unsortedBuilder) AddWithValue(word string, value uint64) {
+	if err := u.TryAddWithValue(word, value); err != nil {
+		panic(err)
+	}
+}
+
+// TryAdd adds a word to the builder. It returns ErrFinished if the builder
+// is finished, or an error if a temporary file cannot be written. Once a
+// temporary file fails, every later call returns the same error, as does
+// Finish.
+func (u *unsortedBuilder) TryAdd(word string) error {
+	return u.add(word, 0)
+}
+
+// TryAddWithValue adds a word and its value to the builder. It returns an
+// error under the same conditions as TryAdd.
+func (u *unsortedBuilder) TryAddWithValue(word string, value uint64) error {
+	if u.builder != nil {
+		return ErrFinished
+	}
+	u.hasValues = true
+	return u.add(word, value)
+}
+
+// AddBytes adds a key of arbitrary bytes to the builder. It will panic under
+// the same conditions as Add, or if words have already been added with Add.
+func (u *unsortedBuilder) AddBytes(word []byte) {
+	if err := u.TryAddBytes(word); err != nil {
+		panic(err)
+	}
+}
+
+// TryAddBytes adds a key of arbitrary bytes to the builder. As with
+// Builder.TryAddBytes, the first word added decides whether the dawg holds
+// bytes or strings. It returns ErrMixedKeys if words have already been added
+// with Add, or an error under the same conditions as TryAdd.
+func (u *unsortedBuilder) TryAddBytes(word []byte) error {
+	if u.builder != nil {
+		return ErrFinished
+	} else if len(u.pending) == 0 && len(u.runs) == 0 {
+		u.byteKeys = true
+	} else if !u.byteKeys {
+		return ErrMixedKeys
+	}
+	return u.add(string(word), 0)
+}
+
+func (u *unsortedBuilder) add(word string, value uint64) error {
+	if u.builder != nil {
+		return ErrFinished
+	} else if u.err != nil {
+		return u.err
+	}
+
+	u.pending = append(u.pending, unsortedEntry{word, value})
+	u.used += len(word) + entryOverhead
+	if u.memoryLimit > 0 && u.used >= u.memoryLimit {
+		// the words were taken from memory, so a run that was not written
+		// in full cannot be recovered.
+		u.err = u.spill()
+	}
+	return u.err
+}
+
+// sortPending sorts the words held in memory and removes duplicates,
+// keeping the value that was added last.
+func (u *unsortedBuilder) sortPending() []unsortedEntry {
+	sort.SliceStable(u.pending, func(i, j int) bool {
+		return compareWords(u.pending[i].word, u.pending[j].word, u.byteKeys) < 0
+	})
+
+	var unique []unsortedEntry
+	for _, entry := range u.pending {
+		if len(unique) > 0 && compareWords(unique[len(unique)-1].word, entry.word, u.byteKeys) == 0 {
+			unique[len(unique)-1] = entry
+		} else {
+			unique = append(unique, entry)
+		}
+	}
+
+	u.pending = nil
+	u.used = 0
+	return unique
+}
+
+// spill writes the words held in memory to a temporary file.
+func (u *unsortedBuilder) spill() error {
+	f, err := os.CreateTemp("", "dawg-run-")
+	if err != nil {
+		return err
+	}
+	u.runs = append(u.runs, f)
+
+	w := bufio.NewWriter(f)
+	buffer := make([]byte, binary.MaxVarintLen64)
+	for _, entry := range u.sortPending() {
+		n := binary.PutUvarint(buffer, uint64(len(entry.word)))
+		w.Write(buffer[:n])
+		w.WriteString(entry.word)
+		n = binary.PutUvarint(buffer, entry.value)
+		w.Write(buffer[:n])
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	_, err = f.Seek(0, io.SeekStart)
+	return err
+}
+
+// Finish merges all of the words and returns a Finder. It will panic if
+// a temporary file cannot be read.
+func (u *unsortedBuilder) Finish() Finder {
+	finder, err := u.FinishE()
+	if err != nil {
+		panic(err)
+	}
+	return finder
+}
+
+// FinishE merges all of the words and returns a Finder, or an error if a
+// temporary file cannot be read.
+func (u *unsortedBuilder) FinishE() (Finder, error) {
+	if err := u.build(); err != nil {
+		return nil, err
+	}
+
+	return u.builder.FinishE()
+}
+
+// FinishTo merges all of the words and writes the dawg to w, without keeping
+// it in memory.
+func (u *unsortedBuilder) FinishTo(w io.Writer) (int64, error) {
+	if err := u.build(); err != nil {
+		return 0, err
+	}
+
+	return u.builder.FinishTo(w)
+}
+
+// FinishToFile merges all of the words and saves the dawg to a file, without
+// keeping it in memory.
+func (u *unsortedBuilder) FinishToFile(filename string) (int64, error) {
+	if err := u.build(); err != nil {
+		return 0, err
+	}
+
+	return u.builder.FinishToFile(filename)
+}
+
+// Abort discards the words added so far and removes the temporary files.
+// Afterward, words cannot be added, and Finish fails with ErrAborted. It does
+// nothing if the builder is finished.
+func (u *unsortedBuilder) Abort() error {
+	if u.builder != nil {
+		return nil
+	}
+
+	err := u.removeRuns()
+	u.pending = nil
+	u.used = 0
+	u.builder = New()
+	u.err = ErrAborted
+	return err
+}
+
+// removeRuns closes and removes the temporary files. It returns the first
+// error from removing them.
+func (u *unsortedBuilder) removeRuns() error {
+	var err error
+	for _, f := range u.runs {
+		f.Close()
+		if removeErr := os.Remove(f.Name()); err == nil {
+			err = removeErr
+		}
+	}
+	u.runs = nil
+	return err
+}
+
+// build merges the words into a new dawg, the first time it is called.
+func (u *unsortedBuilder) build() error {
+	if u.builder == nil && u.err != nil {
+		// a run could not be written, so the words are incomplete
+		u.removeRuns()
+		u.pending = nil
+		u.used = 0
+	} else if u.builder == nil {
+		builder := New().(*dawg)
+		builder.byteKeys = u.byteKeys
+		u.builder = builder
+		u.err = u.merge(u.addEntry)
+	}
+	return u.err
+}
+
+// addEntry adds a merged word to the builder
+func (u *unsortedBuilder) addEntry(entry unsortedEntry) error {
+	if u.hasValues {
+		return u.builder.TryAddWithValue(entry.word, entry.value)
+	}
+	return u.builder.TryAdd(entry.word)
+}
+
+// merge calls fn with each unique word in alphabetical order, and removes
+// the temporary files.
+func (u *unsortedBuilder) merge(fn func(entry unsortedEntry) error) error {
+	defer u.removeRuns()
+
+	runs := &runHeap{byteKeys: u.byteKeys}
+	for i, f := range u.runs {
+		runs.runs = append(runs.runs, &run{r: bufio.NewReader(f), order: i})
+	}
+	runs.runs = append(runs.runs, &run{entries: u.sortPending(), order: len(u.runs)})
+
+	// prime each run with its first word
+	for i := 0; i < len(runs.runs); {
+		ok, err := runs.runs[i].next()
+		if err != nil {
+			return err
+		} else if ok {
+			i++
+		} else {
+			runs.runs = append(runs.runs[:i], runs.runs[i+1:]...)
+		}
+	}
+
+	heap.Init(runs)
+	for runs.Len() > 0 {
+		// the most recent run sorts first, so its value is kept.
+		entry := runs.runs[0].entry
+		if err := fn(entry); err != nil {
+			return err
+		}
+
+		for runs.Len() > 0 && compareWords(runs.runs[0].entry.word, entry.word, u.byteKeys) == 0 {
+			ok, err := runs.runs[0].next()
+			if err != nil {
+				return err
+			} else if ok {
+				heap.Fix(runs, 0)
+			} else {
+				heap.Pop(runs)
+			}
+		}
+	}
+
+	return nil
+}
+
+// run is a sorted list of words, either from a temporary file or held in
+// memory
+type run struct {
+	r       *bufio.Reader
+	entries []unsortedEntry
+	entry   unsortedEntry
+	order   int
+}
+
+// next reads the next word of the run into entry. It returns false at the
+// end of the run.
+func (r *run) next() (bool, error) {
+	if r.r == nil {
+		if len(r.entries) == 0 {
+			return false, nil
+		}
+		r.entry = r.entries[0]
+		r.entries = r.entries[1:]
+		return true, nil
+	}
+
+	length, err := binary.ReadUvarint(r.r)
+	if err == io.EOF {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	word := make([]byte, length)
+	if _, err = io.ReadFull(r.r, word); err != nil {
+		return false, err
+	}
+
+	value, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return false, err
+	}
+
+	r.entry = unsortedEntry{string(word), value}
+	return true, nil
+}
+
+// runHeap orders runs by their current word. Runs with the same word are
+// ordered with the most recent first.
+type runHeap struct {
+	runs     []*run
+	byteKeys bool
+}
+
+func (h *runHeap) Len() int { return len(h.runs) }
+
+func (h *runHeap) Less(i, j int) bool {
+	if c := compareWords(h.runs[i].entry.word, h.runs[j].entry.word, h.byteKeys); c != 0 {
+		return c < 0
+	}
+	return h.runs[i].order > h.runs[j].order
+}
+
+func (h *runHeap) Swap(i, j int) { h.runs[i], h.runs[j] = h.runs[j], h.runs[i] }
+
+func (h *runHeap) Push(x interface{}) { h.runs = append(h.runs, x.(*run)) }
+
+func (h *runHeap) Pop() interface{} {
+	x := h.runs[len(h.runs)-1]
+	h.runs = h.runs[:len(h.runs)-1]
+	return x
+}
+
+// compareWords compares two words in the order the builder adds them: by
+// their runes, or by their bytes if byteKeys is set. Like Add, it treats each
+// byte of invalid UTF-8 as U+FFFD, so words that differ only in their invalid
+// bytes are equal.
+func compareWords(a, b string, byteKeys bool) int {
+	if byteKeys || a == b {
+		return strings.Compare(a, b)
+	}
+
+	for len(a) > 0 && len(b) > 0 {
+		ra, na := utf8.DecodeRuneInString(a)
+		rb, nb := utf8.DecodeRuneInString(b)
+		if ra != rb {
+			if ra < rb {
+				return -1
+			}
+			return 1
+		}
+		a, b = a[na:], b[nb:]
+	}
+
+	return len(a) - len(b)
+}
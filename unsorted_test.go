@@ -0,0 +1,146 @@
+package dawg_test
+
+import (
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/smhanov/dawg"
+)
+
+func TestUnsortedBuilder(t *testing.T) {
+	var words []string
+	for i := 0; i < 2000; i++ {
+		words = append(words, string(rune('a'+i%26))+string(rune('a'+i/26%26))+string(rune('a'+i*7%5)))
+	}
+
+	unique := map[string]bool{}
+	for _, word := range words {
+		unique[word] = true
+	}
+
+	var sorted []string
+	for word := range unique {
+		sorted = append(sorted, word)
+	}
+	sort.Strings(sorted)
+
+	// a small memory limit forces several runs to be written to disk
+	builder := dawg.NewUnsortedBuilder(4096)
+	rand.New(rand.NewSource(1)).Shuffle(len(words), func(i, j int) {
+		words[i], words[j] = words[j], words[i]
+	})
+	for _, word := range words {
+		builder.Add(word)
+	}
+
+	testDawg(t, builder.Finish(), sorted)
+}
+
+func TestUnsortedBuilderValues(t *testing.T) {
+	builder := dawg.NewUnsortedBuilder(64)
+	builder.AddWithValue("cat", 1)
+	builder.AddWithValue("blip", 2)
+	builder.AddWithValue("cat", 3)
+	builder.AddWithValue("apple", 4)
+	builder.AddWithValue("blip", 5)
+
+	finder := builder.Finish()
+	testDawg(t, finder, []string{"apple", "blip", "cat"})
+
+	for word, value := range map[string]uint64{"apple": 4, "blip": 5, "cat": 3} {
+		if got, _ := finder.Value(word); got != value {
+			t.Errorf("Value(%q) returned %d, expected %d", word, got, value)
+		}
+	}
+}
+
+func TestUnsortedBuilderInvalidUTF8(t *testing.T) {
+	// "\xff" and "\xfe" both become U+FFFD, which sorts before U+FFFF even
+	// though its bytes do not.
+	for _, memoryLimit := range []int{0, 1} {
+		builder := dawg.NewUnsortedBuilder(memoryLimit)
+		builder.AddWithValue("\uFFFF", 1)
+		builder.AddWithValue("\xff", 2)
+		builder.AddWithValue("a", 3)
+		builder.AddWithValue("\xfe", 4)
+
+		finder, err := builder.FinishE()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		testDawg(t, finder, []string{"a", "\uFFFD", "\uFFFF"})
+		if value, _ := finder.Value("\uFFFD"); value != 4 {
+			t.Errorf("Value returned %d, expected the value added last", value)
+		}
+	}
+}
+
+func TestUnsortedBuilderSpillError(t *testing.T) {
+	// temporary files cannot be created in a directory that does not exist
+	t.Setenv("TMPDIR", filepath.Join(t.TempDir(), "missing"))
+
+	builder := dawg.NewUnsortedBuilder(1)
+	err := builder.TryAdd("cat")
+	if err == nil {
+		t.Fatal("TryAdd did not fail")
+	}
+
+	// the words that were lost are not silently left out
+	if err2 := builder.TryAdd("dog"); err2 != err {
+		t.Errorf("TryAdd returned %v, expected %v", err2, err)
+	}
+	if _, err2 := builder.FinishE(); err2 != err {
+		t.Errorf("FinishE returned %v, expected %v", err2, err)
+	}
+	if _, err2 := builder.FinishTo(io.Discard); err2 != err {
+		t.Errorf("FinishTo returned %v, expected %v", err2, err)
+	}
+}
+
+func TestUnsortedBuilderAbort(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+
+	builder := dawg.NewUnsortedBuilder(1)
+	builder.Add("cat")
+	builder.Add("blip")
+
+	if files, _ := os.ReadDir(dir); len(files) != 2 {
+		t.Fatalf("expected 2 temporary files, found %d", len(files))
+	}
+
+	if err := builder.Abort(); err != nil {
+		t.Fatal(err)
+	}
+
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("Abort left %d temporary files", len(files))
+	}
+	if err := builder.TryAdd("dog"); err != dawg.ErrFinished {
+		t.Errorf("TryAdd returned %v, expected ErrFinished", err)
+	}
+	if _, err := builder.FinishE(); err != dawg.ErrAborted {
+		t.Errorf("FinishE returned %v, expected ErrAborted", err)
+	}
+
+	sorted := dawg.New()
+	sorted.Add("cat")
+	sorted.Abort()
+	if _, err := sorted.FinishE(); err != dawg.ErrAborted {
+		t.Errorf("FinishE returned %v, expected ErrAborted", err)
+	}
+
+	// after Finish, Abort does nothing
+	sorted = dawg.New()
+	sorted.Add("cat")
+	finder := sorted.Finish()
+	if err := sorted.Abort(); err != nil {
+		t.Fatal(err)
+	}
+	testDawg(t, finder, []string{"cat"})
+}
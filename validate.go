@@ -0,0 +1,262 @@
+package dawg
+
+import (
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+// ValidationReport describes a dawg that was checked by Validate.
+type ValidationReport struct {
+	// Format version of the file
+	Version int
+
+	// Number of words, nodes and edges, as recorded in the header
+	Words int
+	Nodes int
+	Edges int
+
+	// Number of edges between the nodes of the graph. Because nodes are
+	// shared between words, this is usually much smaller than Edges, which
+	// counts the edges the words would need if nothing was shared.
+	GraphEdges int
+
+	// Length of the longest word, in characters
+	MaxLength int
+}
+
+// Validate checks the structure of the dawg at the start of r without
+// trusting any of it. Every node is decoded, and Validate makes sure that
+// addresses lie within the file, the characters of each node's edges are
+// strictly increasing, the skip counts agree with the number of words beneath
+// each node, the counts in the header are correct, and the graph has no
+// cycles. A file that passes can be queried without reading out of range or
+// looping forever.
+//
+// Problems with the structure are reported as errors that wrap ErrCorrupt.
+// Validate does not check the checksum. Use Verify for that.
+func Validate(r io.ReaderAt) (*ValidationReport, error) {
+	h, err := readHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.numNodes < 1 {
+		return nil, corruptf("there are no nodes")
+	}
+
+	d := newReader(r, h)
+	seeker := newBitSeeker(r)
+	end := h.valuesOffset() * 8
+
+	// decode the nodes in order, like DumpFile, recording where each begins.
+	// Each node takes at least two bits, so a bad node count soon runs past
+	// the end.
+	starts := make(map[int64]int)
+	var addresses []int64
+	pos := h.firstNodeOffset
+	for i := 0; i < h.numNodes; i++ {
+		starts[pos] = i
+		addresses = append(addresses, pos)
+		if pos, err = d.checkNode(&seeker, pos, end); err != nil {
+			return nil, err
+		}
+	}
+
+	if (pos+7)/8 != h.valuesOffset() {
+		return nil, corruptf("nodes end at bit %d, but the node area ends at bit %d", pos, end)
+	}
+
+	// walk the graph depth first. Each node is checked after its children,
+	// so that the number of words beneath them is known.
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	type frame struct {
+		id   int
+		node nodeResult
+		next int
+	}
+
+	state := make([]uint8, h.numNodes)
+	counts := make([]uint64, h.numNodes)
+	lengths := make([]int, h.numNodes)
+	var order []int
+	graphEdges := 0
+
+	state[0] = visiting
+	stack := []frame{{id: 0, node: d.getNode(&seeker, addresses[0])}}
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		if top.next < len(top.node.edges) {
+			edge := top.node.edges[top.next]
+			top.next++
+			child, ok := starts[edge.node]
+			if !ok {
+				return nil, corruptf("node at bit %d has an edge to bit %d, which is not a node",
+					addresses[top.id], edge.node)
+			}
+
+			switch state[child] {
+			case visiting:
+				return nil, corruptf("node at bit %d is part of a cycle", edge.node)
+			case unvisited:
+				state[child] = visiting
+				stack = append(stack, frame{id: child, node: d.getNode(&seeker, edge.node)})
+			}
+			continue
+		}
+
+		var count uint64
+		if top.node.final {
+			count = 1
+		}
+
+		for i, edge := range top.node.edges {
+			if edge.ch < 0 {
+				return nil, corruptf("edge of node at bit %d has a character that is not in the alphabet",
+					addresses[top.id])
+			}
+
+			if i > 0 && edge.ch <= top.node.edges[i-1].ch {
+				return nil, corruptf("edges of node at bit %d are not in order", addresses[top.id])
+			}
+
+			if uint64(edge.count) != count {
+				return nil, corruptf("edge '%c' of node at bit %d skips %d words instead of %d",
+					edge.ch, addresses[top.id], uint64(edge.count), count)
+			}
+
+			child := starts[edge.node]
+			count += counts[child]
+			if count > uint64(h.numAdded) {
+				return nil, corruptf("node at bit %d has too many words", addresses[top.id])
+			}
+
+			if lengths[child]+1 > lengths[top.id] {
+				lengths[top.id] = lengths[child] + 1
+			}
+		}
+
+		if count == 0 && top.id != 0 {
+			return nil, corruptf("node at bit %d does not lead to any words", addresses[top.id])
+		}
+
+		counts[top.id] = count
+		state[top.id] = visited
+		order = append(order, top.id)
+		graphEdges += len(top.node.edges)
+		stack = stack[:len(stack)-1]
+	}
+
+	if counts[0] != uint64(h.numAdded) {
+		return nil, corruptf("header has %d words but the graph has %d", h.numAdded, counts[0])
+	}
+
+	if len(order) != h.numNodes {
+		return nil, corruptf("%d nodes cannot be reached", h.numNodes-len(order))
+	}
+
+	// The header counts the edges the words would need if nothing was
+	// shared. This is the number of paths through each edge of the graph,
+	// found by visiting the nodes in topological order.
+	paths := make([]uint64, h.numNodes)
+	paths[0] = 1
+	var prefixes uint64
+	for i := len(order) - 1; i >= 0; i-- {
+		id := order[i]
+		for _, edge := range d.getNode(&seeker, addresses[id]).edges {
+			child := starts[edge.node]
+			prefixes += paths[id]
+			paths[child] += paths[id]
+			if prefixes > uint64(h.numEdges) {
+				return nil, corruptf("header has %d edges but the graph has more", h.numEdges)
+			}
+		}
+	}
+
+	if prefixes != uint64(h.numEdges) {
+		return nil, corruptf("header has %d edges but the graph has %d", h.numEdges, prefixes)
+	}
+
+	return &ValidationReport{
+		Version:    h.version,
+		Words:      h.numAdded,
+		Nodes:      h.numNodes,
+		Edges:      h.numEdges,
+		GraphEdges: graphEdges,
+		MaxLength:  lengths[0],
+	}, nil
+}
+
+// checkNode makes sure that the node at pos lies entirely before end, so that
+// it can be safely decoded by getNode. It returns the position after the
+// node.
+func (d *dawg) checkNode(r *bitSeeker, pos, end int64) (int64, error) {
+	if pos+3 > end {
+		return 0, corruptf("node at bit %d is past the end of the nodes", pos)
+	}
+
+	r.Seek(pos, 0)
+	r.ReadBits(1)
+	fallthr := r.ReadBits(1) == 1
+	if fallthr && !d.aligned {
+		next := r.Tell() + d.cbits
+		if next > end {
+			return 0, corruptf("node at bit %d is past the end of the nodes", pos)
+		}
+		return next, nil
+	}
+
+	numEdges := uint64(1)
+	nskip := int64(0)
+	if d.aligned {
+		// the header has a fixed width, and fallthrough nodes are not used
+		if fallthr || pos%8 != 0 || pos+8+d.cbits > end {
+			return 0, corruptf("node at bit %d has a bad header", pos)
+		}
+
+		list := d.readEdgeList(r, pos)
+		numEdges, nskip = uint64(list.count), list.nskip
+		if nskip > 64 {
+			return 0, corruptf("node at bit %d has a bad edge count", pos)
+		}
+	} else if r.ReadBits(1) != 1 {
+		nskiplen := int64(bits.Len(uint(d.wbits)))
+		start := r.Tell()
+		numEdges = readUnsigned(r)
+		nskip = int64(r.ReadBits(nskiplen))
+		if r.Tell() > end || r.Tell()-start > 10*8+nskiplen || nskip > 64 {
+			return 0, corruptf("node at bit %d has a bad edge count", pos)
+		}
+	}
+
+	if r.Tell() > end {
+		return 0, corruptf("node at bit %d is past the end of the nodes", pos)
+	}
+
+	// the first edge has no skip count
+	edgeBits := d.cbits + nskip + d.abits
+	if numEdges > uint64(end-r.Tell()+nskip)/uint64(edgeBits) {
+		return 0, corruptf("node at bit %d has too many edges", pos)
+	}
+
+	next := r.Tell() + int64(numEdges)*edgeBits
+	if numEdges > 0 {
+		next -= nskip
+	}
+
+	if next > end {
+		return 0, corruptf("node at bit %d is past the end of the nodes", pos)
+	}
+	return next, nil
+}
+
+// corruptf returns an error wrapping ErrCorrupt, with a description of the
+// problem
+func corruptf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: "+format, append([]interface{}{ErrCorrupt}, args...)...)
+}
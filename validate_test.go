@@ -0,0 +1,68 @@
+package dawg_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/smhanov/dawg"
+)
+
+func TestValidate(t *testing.T) {
+	words := []string{"", "blip", "cat", "catnip", "cats", "dog", "dogs"}
+
+	builder := dawg.New()
+	for i, word := range words {
+		builder.AddWithValue(word, uint64(i))
+	}
+
+	var buffer bytes.Buffer
+	if _, err := builder.Finish().Write(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buffer.Bytes()
+	report, err := dawg.Validate(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Words != len(words) || report.MaxLength != 6 || report.Edges != 15 {
+		t.Errorf("Validate returned %+v", report)
+	}
+
+	// Flipping any bit must be detected, or leave a dawg that can be used
+	// safely.
+	for i := 18 * 8; i < len(data)*8; i++ {
+		corrupt := append([]byte{}, data...)
+		corrupt[i/8] ^= 1 << (i % 8)
+		_, err := dawg.Validate(bytes.NewReader(corrupt))
+		if err == nil {
+			finder, err := dawg.Read(bytes.NewReader(corrupt), 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			finder.Enumerate(func(index int, word []rune, final bool) dawg.EnumerationResult {
+				return dawg.Continue
+			})
+		} else if !errors.Is(err, dawg.ErrCorrupt) {
+			t.Errorf("Validate with bit %d flipped returned %v", i, err)
+		}
+	}
+}
+
+func TestValidateVersion1(t *testing.T) {
+	data := []byte{0x0, 0x0, 0x0, 0x1c, 0x7, 0x8, 0x5, 0x8, 0xb, 0x80, 0x5e,
+		0x27, 0x6c, 0x6a, 0x79, 0xd8, 0xe9, 0x78, 0x40, 0x5, 0xc2, 0xf4, 0x80,
+		0x56, 0xe7, 0xfe, 0x74, 0x88}
+
+	report, err := dawg.Validate(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Version != 1 || report.Words != 5 || report.Nodes != 8 {
+		t.Errorf("Validate returned %+v", report)
+	}
+}
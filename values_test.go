@@ -0,0 +1,59 @@
+package dawg_test
+
+import (
+	"testing"
+
+	"github.com/smhanov/dawg"
+)
+
+func TestValues(t *testing.T) {
+	builder := dawg.New()
+	builder.AddWithValue("", 7)
+	builder.Add("blip")
+	builder.AddWithValue("cat", 1<<40)
+	builder.AddWithValue("catnip", 3)
+	builder.Add("cats")
+
+	shouldbe := map[string]uint64{
+		"":       7,
+		"blip":   0,
+		"cat":    1 << 40,
+		"catnip": 3,
+		"cats":   0,
+	}
+
+	finder := builder.Finish()
+	if _, err := finder.Save("test.dawg"); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := dawg.Load("test.dawg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer saved.Close()
+
+	for _, f := range []dawg.Finder{finder, saved} {
+		for word, value := range shouldbe {
+			got, ok := f.Value(word)
+			if !ok || got != value {
+				t.Errorf("Value(%q) returned %v, %v, expected %v", word, got, ok, value)
+			}
+		}
+
+		if _, ok := f.Value("ca"); ok {
+			t.Errorf("Value(\"ca\") should not be found")
+		}
+
+		if f.IndexOf("catnip") != 3 {
+			t.Errorf("IndexOf(\"catnip\") returned %d", f.IndexOf("catnip"))
+		}
+	}
+}
+
+func TestNoValues(t *testing.T) {
+	finder := createDawg([]string{"a", "b"})
+	if value, ok := finder.Value("b"); !ok || value != 0 {
+		t.Errorf("Value(\"b\") returned %v, %v", value, ok)
+	}
+}